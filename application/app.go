@@ -5,16 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"github.com/archine/gin-plus/v3/banner"
+	"github.com/archine/gin-plus/v3/event"
 	"github.com/archine/gin-plus/v3/exception"
 	"github.com/archine/gin-plus/v3/mvc"
 	"github.com/archine/gin-plus/v3/plugin/logger"
 	"github.com/archine/ioc"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -22,50 +26,81 @@ import (
 // App application instance
 type App struct {
 	e              *gin.Engine
-	preApplyFunc   func()
-	preStartFunc   func()
-	preStopFunc    func()
+	hooks          map[Phase][]Hook
+	beans          []any
+	lifecycleBeans []any
+	startedBeans   []SmartLifecycle
+	startupChecks  []StartupCheck
 	exitDelay      time.Duration
+	preStopDelay   time.Duration
 	interceptors   []mvc.MethodInterceptor
 	ginMiddlewares []gin.HandlerFunc
 	server         *http.Server
+	configDumpPath string
+	certReloader   *certReloader
+
+	managementServer *http.Server
+	managementRoutes []func(r gin.IRouter)
+	healthIndicators []HealthIndicator
+
+	addr           net.Addr
+	managementAddr net.Addr
+	serveErr       chan error
+	listeners      []net.Listener
+	listenerNames  []string
+
+	ready          atomic.Bool
+	inFlight       atomic.Int64
+	baseCtx        context.Context
+	cancelBase     context.CancelFunc
+	shutdownReport *ShutdownReport
 }
 
-// New Create a clean application, you can add some gin middlewares to the engine
+// New Create a clean application, you can add some gin middlewares to the engine.
+// The viper options are passed to the configuration reader, use NewWithConfig for the other config options.
 func New(confOptions []viper.Option, middlewares ...gin.HandlerFunc) *App {
-	LoadApplicationConfigFile(confOptions)
+	return NewWithConfig([]ConfigOption{WithViperOptions(confOptions...)}, middlewares...)
+}
+
+// NewWithConfig Create a clean application with the config options, such as the config file and sources
+func NewWithConfig(confOptions []ConfigOption, middlewares ...gin.HandlerFunc) *App {
+	if err := LoadApplicationConfigFile(confOptions...); err != nil {
+		logger.Log.Fatal(err.Error())
+	}
 	if Conf.Server.Env == Prod {
 		gin.SetMode(gin.ReleaseMode)
 	} else {
 		gin.SetMode(gin.DebugMode)
 	}
-	return &App{
-		exitDelay:      3 * time.Second,
+	app := &App{
+		exitDelay:      Conf.Server.Shutdown.Timeout,
+		preStopDelay:   Conf.Server.Shutdown.PreStopDelay,
 		ginMiddlewares: middlewares,
-		server: &http.Server{
-			Addr:                         fmt.Sprintf(":%d", Conf.Server.Port),
-			ReadTimeout:                  Conf.Server.ReadTimeout,
-			WriteTimeout:                 Conf.Server.WriteTimeout,
-			DisableGeneralOptionsHandler: true,
-		},
+		server:         newServer(),
+	}
+	app.server.SetKeepAlivesEnabled(Conf.Server.KeepAlive)
+	if Conf.Server.TLS.Enabled() {
+		tlsConf, reloader, err := buildTLSConfig(&Conf.Server.TLS)
+		if err != nil {
+			logger.Log.Fatalf("Init server tls error, %s", err.Error())
+		}
+		app.server.TLSConfig = tlsConf
+		app.certReloader = reloader
 	}
+	return app
 }
 
-// Default Create a default application with gin default logger, exception interception, and cross-domain middleware
+// Default Create a default application with gin default logger, exception interception, and cross-domain middleware.
+// The cross-domain policy is read from the cors block of the configuration, cross-origin requests are not allowed by default.
 func Default(confOptions ...viper.Option) *App {
-	return New(
-		confOptions,
-		gin.Logger(),
-		exception.GlobalExceptionInterceptor,
-		cors.New(cors.Config{
-			AllowMethods:     []string{"PUT", "PATCH", "POST", "GET", "DELETE", "OPTIONS", "HEAD"},
-			AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-			ExposeHeaders:    []string{"Content-Length"},
-			AllowCredentials: true,
-			AllowOriginFunc: func(origin string) bool {
-				return true
-			},
-		}))
+	return DefaultWithConfig(WithViperOptions(confOptions...))
+}
+
+// DefaultWithConfig Create a default application with the config options, such as the config file and sources
+func DefaultWithConfig(confOptions ...ConfigOption) *App {
+	app := NewWithConfig(confOptions, gin.Logger(), exception.GlobalExceptionInterceptor)
+	app.ginMiddlewares = append(app.ginMiddlewares, newCorsMiddleware(&Conf.Cors))
+	return app
 }
 
 // Banner Sets the project startup banner
@@ -87,13 +122,84 @@ func (a *App) Interceptor(interceptor ...mvc.MethodInterceptor) *App {
 	return a
 }
 
-// Run the main program entry
+// Run the main program entry, block until SIGTERM or SIGINT is received and then shut down gracefully
 func (a *App) Run() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	if err := a.RunContext(ctx); err != nil {
+		logger.Log.Fatalf("Application run error, %s", err.Error())
+	}
+}
+
+// RunContext start the application and block until the ctx is cancelled or the server fails, then shut down gracefully.
+// The shutdown is bounded by PreStopDelay plus twice the ExitDelay, one to drain the requests
+// and one to stop the beans and wait for the async event listeners.
+func (a *App) RunContext(ctx context.Context) error {
+	if err := a.Start(ctx); err != nil {
+		return err
+	}
+	restartCh, stopRestart := a.notifyRestart()
+	defer stopRestart()
+	var serveErr error
+	var child *restartChild
+	// nil channels block until a child process is started
+	var takeover <-chan os.Signal
+	var childExited <-chan error
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			break wait
+		case serveErr = <-a.serveErr:
+			break wait
+		case <-takeover:
+			logger.Log.Debug("Graceful restart, the child process has taken over")
+			break wait
+		case err := <-childExited:
+			logger.Log.Errorf("Graceful restart failed, the child process exited before taking over, %s", err.Error())
+			child.stop()
+			child, takeover, childExited = nil, nil, nil
+		case <-restartCh:
+			if child != nil {
+				logger.Log.Warn("Graceful restart in progress, waiting for the child process to take over ...")
+				continue
+			}
+			// keep serving until the child process is started and asks to stop
+			c, err := a.restart()
+			if err != nil {
+				logger.Log.Errorf("Graceful restart failed, %s", err.Error())
+				continue
+			}
+			child, takeover, childExited = c, c.takeover, c.exited
+			logger.Log.Debug("Graceful restart, waiting for the child process to take over ...")
+		}
+	}
+	if child != nil {
+		child.stop()
+	}
+	shutdownCtx, cancelFunc := context.WithTimeout(context.Background(), a.preStopDelay+a.exitDelay)
+	defer cancelFunc()
+	return errors.Join(serveErr, a.Shutdown(shutdownCtx))
+}
+
+// Start initialize the engine, apply all controllers and start serving without blocking.
+// It returns when the servers are listening, use Addr to get the actual address when the port is 0.
+// Serving errors after start are reported by RunContext.
+func (a *App) Start(ctx context.Context) error {
+	if a.e != nil {
+		return errors.New("application already started")
+	}
 	if logger.Log == nil {
 		logger.Log = &logger.DefaultLog{}
 	}
 	a.e = gin.New()
+	initMetrics()
+	a.e.Use(metricsMiddleware, a.inFlightMiddleware)
+	a.initBaseContext()
 	a.server.Handler = a.e
+	if Conf.Server.H2C && a.server.TLSConfig == nil {
+		a.server.Handler = h2c.NewHandler(a.e, &http2.Server{})
+	}
 	if len(a.ginMiddlewares) > 0 {
 		a.e.Use(a.ginMiddlewares...)
 	}
@@ -103,12 +209,19 @@ func (a *App) Run() {
 	if banner.Banner != "" {
 		fmt.Print(banner.Banner)
 	}
-	if a.preApplyFunc != nil {
-		a.preApplyFunc()
+	if err := a.runHooks(ctx, PreApplyPhase); err != nil {
+		return fmt.Errorf("application start aborted, %w", err)
 	}
 	if len(a.interceptors) > 0 {
 		a.e.Use(func(context *gin.Context) {
 			var is []mvc.MethodInterceptor
+			defer func() {
+				for i := len(is) - 1; i >= 0; i-- {
+					if c, ok := is[i].(mvc.CompletionInterceptor); ok {
+						c.AfterCompletion(context)
+					}
+				}
+			}()
 			for _, interceptor := range a.interceptors {
 				if interceptor.Predicate(context) {
 					is = append(is, interceptor)
@@ -128,34 +241,94 @@ func (a *App) Run() {
 		})
 	}
 	mvc.Apply(a.e, true)
-	if a.preStartFunc != nil {
-		a.preStartFunc()
+	if a.configDumpPath != "" {
+		a.e.GET(a.configDumpPath, ConfigDumpHandler)
+	}
+	if err := a.startBeans(ctx); err != nil {
+		return fmt.Errorf("application start aborted, %w", err)
+	}
+	if err := a.runHooks(ctx, PreStartPhase); err != nil {
+		return errors.Join(fmt.Errorf("application start aborted, %w", err), a.stopBeans(context.Background()))
+	}
+	if err := a.runStartupChecks(ctx); err != nil {
+		return errors.Join(fmt.Errorf("application start aborted, %w", err), a.stopBeans(context.Background()))
 	}
-	go func() {
-		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Log.Fatalf("Application start error, %s", err.Error())
+	if err := ctx.Err(); err != nil {
+		return errors.Join(err, a.stopBeans(context.Background()))
+	}
+	ln, err := a.listen("main", a.server, Conf.Server.Unix)
+	if err != nil {
+		return errors.Join(fmt.Errorf("application start error, %w", err), a.stopBeans(context.Background()))
+	}
+	a.addr = ln.Addr()
+	a.serveErr = make(chan error, 2)
+	go a.serve(func() error { return serve(a.server, ln) })
+	logger.Log.Debugf("Application start success on [%s]", a.addr.String())
+	if a.managementServer = a.newManagementServer(); a.managementServer != nil {
+		mln, err := a.listen("management", a.managementServer, "")
+		if err != nil {
+			_ = a.server.Close()
+			return errors.Join(fmt.Errorf("management server start error, %w", err), a.stopBeans(context.Background()))
 		}
-	}()
-	logger.Log.Debugf("Application start success on Ports:[%d]", Conf.Server.Port)
-	quit := make(chan os.Signal)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
-	logger.Log.Debug("Shutdown server ...")
-	if a.preStopFunc != nil {
-		a.preStopFunc()
-	}
-	ctx, cancelFunc := context.WithTimeout(context.Background(), a.exitDelay)
-	defer cancelFunc()
-	if err := a.server.Shutdown(ctx); err != nil {
-		logger.Log.Fatalf("Server shutdown failure, %s", err.Error())
+		a.managementAddr = mln.Addr()
+		go a.serve(func() error { return a.managementServer.Serve(mln) })
+		logger.Log.Debugf("Management server start success on [%s]", a.managementAddr.String())
+	}
+	a.ready.Store(true)
+	if err := event.Publish(ctx, event.ApplicationStarted{Addr: a.addr, ManagementAddr: a.managementAddr}); err != nil {
+		logger.Log.Errorf("Publish application started event error, %s", err.Error())
+	}
+	if err := notifyParent(); err != nil {
+		logger.Log.Warnf("Notify the parent process to stop failed, %s", err.Error())
+	}
+	return nil
+}
+
+// serve run the server and report the unexpected error
+func (a *App) serve(f func() error) {
+	if err := f(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		a.serveErr <- fmt.Errorf("server stopped unexpectedly, %w", err)
 	}
-	logger.Log.Debug("Server exiting ...")
+}
+
+// Addr the actual address of the main server, nil before the application is started
+func (a *App) Addr() net.Addr {
+	return a.addr
+}
+
+// ManagementAddr the actual address of the management server, nil when it is disabled or not started
+func (a *App) ManagementAddr() net.Addr {
+	return a.managementAddr
+}
+
+// Engine the gin engine of the application, nil before the application is started
+func (a *App) Engine() *gin.Engine {
+	return a.e
+}
+
+// ExposeConfig Register a GET endpoint that responds the effective configuration with secrets masked.
+// Global interceptors are applied to it, remember to protect it in production.
+func (a *App) ExposeConfig(path string) *App {
+	a.configDumpPath = path
+	return a
+}
+
+// ManagementRoute Add admin endpoints to the management server, the router is prefixed with management.base_path
+func (a *App) ManagementRoute(f func(r gin.IRouter)) *App {
+	a.managementRoutes = append(a.managementRoutes, f)
+	return a
+}
+
+// HealthIndicator Add indicators to the health endpoint of the management server
+func (a *App) HealthIndicator(indicator ...HealthIndicator) *App {
+	a.healthIndicators = append(a.healthIndicators, indicator...)
+	return a
 }
 
 // ReadConfig Read configuration
-// v config struct pointer
+// v config struct pointer, a bare number of a duration means seconds as in the server block
 func (a *App) ReadConfig(v any) *App {
-	if err := GetConfReader().Unmarshal(v); err != nil {
+	if err := GetConfReader().Unmarshal(v, decodeHook()); err != nil {
 		logger.Log.Fatalf("read config error, %s", err.Error())
 	}
 	return a
@@ -164,34 +337,35 @@ func (a *App) ReadConfig(v any) *App {
 // PreApply triggered before mvc starts, Before the project starts.
 // This is where you can provide basic services, such as set beans.
 // Of course, you can also perform logic here that doesn't require obtaining beans.
+// It can be called multiple times, the funcs run in the order they are added, use Hook for ordering and errors.
 func (a *App) PreApply(f func()) *App {
 	if f == nil {
 		logger.Log.Fatalf("apply before func cannot be null.")
 	}
-	a.preApplyFunc = f
-	return a
+	return a.Hook(PreApplyPhase, Hook{Func: wrapHook(f)})
 }
 
 // PreStart The last event before the project starts, dependency injection is all finished and ready to run.
 // You can execute any logic here.
+// It can be called multiple times, the funcs run in the order they are added, use Hook for ordering and errors.
 func (a *App) PreStart(f func()) *App {
-	a.preStartFunc = f
-	return a
+	return a.Hook(PreStartPhase, Hook{Func: wrapHook(f)})
 }
 
 // PreStop The event before the application stops can be performed here to close some resources
+// It can be called multiple times, the funcs run in the order they are added, use Hook for ordering and errors.
 func (a *App) PreStop(f func()) *App {
-	a.preStopFunc = f
-	return a
+	return a.Hook(PreStopPhase, Hook{Func: wrapHook(f)})
 }
 
 // PostStop Events after the application has stopped can perform other closing operations here
+// It can be called multiple times, the funcs run in the order they are added, use Hook for ordering and errors.
 func (a *App) PostStop(f func()) *App {
-	a.server.RegisterOnShutdown(f)
-	return a
+	return a.Hook(PostStopPhase, Hook{Func: wrapHook(f)})
 }
 
-// ExitDelay Graceful exit time(default 3s), when reached to shut down the server and trigger PostStop().
+// ExitDelay Graceful exit time(default server.shutdown.timeout 3s) to drain in-flight requests,
+// when reached the remaining requests are cancelled and PostStop() is triggered.
 func (a *App) ExitDelay(time time.Duration) *App {
 	a.exitDelay = time
 	return a
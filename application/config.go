@@ -1,10 +1,18 @@
 package application
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"github.com/archine/gin-plus/v3/event"
 	"github.com/archine/gin-plus/v3/plugin/logger"
 	ioc "github.com/archine/ioc"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
 	"time"
 )
 
@@ -21,36 +29,249 @@ const (
 	Prod = "prod"
 )
 
+const (
+	// DefaultConfigFile the configuration file used when no other path is specified
+	DefaultConfigFile = "app.yml"
+	// ConfigFileEnv the environment variable used to specify the configuration file path
+	ConfigFileEnv = "GIN_PLUS_CONFIG"
+	// ConfigFlagName the flag name used to specify the configuration file path
+	ConfigFlagName = "c"
+)
+
+// Durations accept a go duration string such as "30s" or "1m", a bare number means seconds.
 type config struct {
 	Server struct {
-		Port         int           `mapstructure:"port"`          // Application port
-		Env          string        `mapstructure:"env"`           // Application environment, default dev, you can set it to prod or test
-		MaxFileSize  int64         `mapstructure:"max_file_size"` // Maximum file size, default 100M
-		WriteTimeout time.Duration `mapstructure:"write_timeout"` // Write timeout, default 0 means no timeout
-		ReadTimeout  time.Duration `mapstructure:"read_timeout"`  // Read timeout, default 0 means no timeout
+		Host              string        `mapstructure:"host"`                // Bind host, default empty means all interfaces
+		Port              int           `mapstructure:"port"`                // Application port
+		Unix              string        `mapstructure:"unix"`                // Unix socket path, takes precedence over host and port when set
+		Env               string        `mapstructure:"env"`                 // Application environment, default dev, you can set it to prod or test
+		MaxFileSize       int64         `mapstructure:"max_file_size"`       // Maximum file size in bytes, default 100M
+		MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`    // Maximum request header size in bytes, default 1M
+		WriteTimeout      time.Duration `mapstructure:"write_timeout"`       // Write timeout, default 0 means no timeout
+		ReadTimeout       time.Duration `mapstructure:"read_timeout"`        // Read timeout, default 0 means no timeout
+		ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"` // Read header timeout, default 0 means the read timeout is used
+		IdleTimeout       time.Duration `mapstructure:"idle_timeout"`        // Keep-alive idle timeout, default 0 means the read timeout is used
+		KeepAlive         bool          `mapstructure:"keep_alive"`          // Whether to enable HTTP keep-alive, default true
+		TLS               TLSConfig     `mapstructure:"tls"`                 // TLS settings, plain http is served when not configured
+		H2C               bool          `mapstructure:"h2c"`                 // Serve HTTP/2 without tls (h2c), ignored when tls is enabled
+		Shutdown          struct {
+			PreStopDelay time.Duration `mapstructure:"pre_stop_delay"` // Wait for load balancers after marking not ready, default 0
+			Timeout      time.Duration `mapstructure:"timeout"`        // Drain timeout of in-flight requests, default 3s
+		} `mapstructure:"shutdown"`
+		Restart struct {
+			Enabled bool   `mapstructure:"enabled"` // Graceful restart by passing the listening sockets to a child process, unix only
+			Signal  string `mapstructure:"signal"`  // Signal triggering the restart, default SIGUSR2, supports SIGHUP, SIGUSR1 and SIGUSR2
+		} `mapstructure:"restart"`
+		StartupCheck struct {
+			Timeout    time.Duration `mapstructure:"timeout"`     // Overall timeout of the startup checks, default 30s
+			Backoff    time.Duration `mapstructure:"backoff"`     // Initial retry interval, doubled after each failure, default 500ms
+			MaxBackoff time.Duration `mapstructure:"max_backoff"` // Maximum retry interval, default 5s
+		} `mapstructure:"startup_check"`
+	}
+	Management struct {
+		Host     string `mapstructure:"host"`      // Bind host, default empty means all interfaces
+		Port     int    `mapstructure:"port"`      // Management server port, default 0 means disabled
+		BasePath string `mapstructure:"base_path"` // Path prefix of management endpoints, default /actuator
+		Pprof    bool   `mapstructure:"pprof"`     // Whether to expose pprof endpoints, default false
+	}
+	Cors CorsConfig `mapstructure:"cors"` // Cross-origin policy of application.Default, cross-origin requests are not allowed by default
+}
+
+// ConfigSource a source of the application configuration
+type ConfigSource interface {
+	// Name of the source, used in logs and error messages
+	Name() string
+
+	// Load merge the configuration of the source into the reader
+	Load(reader *viper.Viper) error
+}
+
+// ConfigOption customize how the application configuration is loaded
+type ConfigOption func(l *configLoader)
+
+type configLoader struct {
+	file         string
+	noFile       bool
+	flagSet      *flag.FlagSet
+	sources      []ConfigSource
+	viperOptions []viper.Option
+}
+
+// WithConfigFile specify the configuration file path, it takes precedence over the flag and the environment variable
+func WithConfigFile(path string) ConfigOption {
+	return func(l *configLoader) {
+		l.file = path
+	}
+}
+
+// WithoutConfigFile do not read the configuration file, only defaults, sources and environment variables are used
+func WithoutConfigFile() ConfigOption {
+	return func(l *configLoader) {
+		l.noFile = true
+	}
+}
+
+// WithConfigFlagSet read the configuration file path from the "-c" flag of the given flag set.
+// The flag must be registered with BindConfigFlag and the flag set parsed before the application is created.
+func WithConfigFlagSet(fs *flag.FlagSet) ConfigOption {
+	return func(l *configLoader) {
+		l.flagSet = fs
+	}
+}
+
+// WithConfigSource add configuration sources layered on top of the configuration file.
+// Sources are merged in the order they are added, the later one overrides the former.
+// When any source is added, the configuration file becomes optional unless its path is explicitly specified.
+func WithConfigSource(sources ...ConfigSource) ConfigOption {
+	return func(l *configLoader) {
+		l.sources = append(l.sources, sources...)
+	}
+}
+
+// WithViperOptions pass options to the underlying viper reader
+func WithViperOptions(options ...viper.Option) ConfigOption {
+	return func(l *configLoader) {
+		l.viperOptions = append(l.viperOptions, options...)
 	}
 }
 
-// LoadApplicationConfigFile load the application configuration file
-func LoadApplicationConfigFile(options []viper.Option) {
-	var configFile string
-	flag.StringVar(&configFile, "c", "app.yml", "Absolute path to the project configuration file, default app.yml")
-	flag.Parse()
-	confReader := viper.NewWithOptions(options...)
-	confReader.SetConfigFile(configFile)
+// BindConfigFlag register the "-c" flag on the given flag set, do nothing if it already exists.
+// Example:
+//
+//	application.BindConfigFlag(flag.CommandLine)
+//	flag.Parse()
+//	application.DefaultWithConfig(application.WithConfigFlagSet(flag.CommandLine)).Run()
+func BindConfigFlag(fs *flag.FlagSet) {
+	if fs.Lookup(ConfigFlagName) != nil {
+		return
+	}
+	fs.String(ConfigFlagName, DefaultConfigFile, "Absolute path to the project configuration file, default app.yml")
+}
+
+// resolveFile the configuration file path, priority: option > flag > environment variable > flag default value.
+// When no flag set is specified, the "-c" flag of the command line is used if the caller has registered and parsed it.
+// The returned explicit is false when falling back to the default file.
+func (l *configLoader) resolveFile() (file string, explicit bool) {
+	if l.file != "" {
+		return l.file, true
+	}
+	fs := l.flagSet
+	if fs == nil && flag.Parsed() {
+		fs = flag.CommandLine
+	}
+	var configFlag *flag.Flag
+	if fs != nil && fs.Parsed() {
+		configFlag = fs.Lookup(ConfigFlagName)
+	}
+	if configFlag != nil {
+		fs.Visit(func(f *flag.Flag) {
+			explicit = explicit || f.Name == ConfigFlagName
+		})
+		if explicit {
+			return configFlag.Value.String(), true
+		}
+	}
+	if env := os.Getenv(ConfigFileEnv); env != "" {
+		return env, true
+	}
+	if configFlag != nil && configFlag.Value.String() != "" {
+		return configFlag.Value.String(), false
+	}
+	return DefaultConfigFile, false
+}
+
+// LoadApplicationConfigFile load the application configuration.
+// Precedence from low to high: defaults < configuration file < sources in order < environment variables
+// Only load and decode failures are returned, the errors of the ConfigChanged listeners are logged.
+func LoadApplicationConfigFile(options ...ConfigOption) error {
+	loader := &configLoader{}
+	for _, option := range options {
+		option(loader)
+	}
+	sources := loader.sources
+	if !loader.noFile {
+		file, explicit := loader.resolveFile()
+		sources = append([]ConfigSource{&FileSource{Path: file, Optional: !explicit && len(loader.sources) > 0}}, sources...)
+	}
+	confReader := viper.NewWithOptions(loader.viperOptions...)
 	confReader.SetDefault("server.port", 4006)
 	confReader.SetDefault("server.env", Dev)
 	confReader.SetDefault("server.max_file_size", 104857600)
-	confReader.SetDefault("server.read_timeout", 0)  // 0 means no timeout
-	confReader.SetDefault("server.write_timeout", 0) // 0 means no timeout
+	confReader.SetDefault("server.max_header_bytes", http.DefaultMaxHeaderBytes)
+	confReader.SetDefault("server.read_timeout", 0)        // 0 means no timeout
+	confReader.SetDefault("server.read_header_timeout", 0) // 0 means the read timeout is used
+	confReader.SetDefault("server.write_timeout", 0)       // 0 means no timeout
+	confReader.SetDefault("server.idle_timeout", 0)        // 0 means the read timeout is used
+	confReader.SetDefault("server.keep_alive", true)
+	confReader.SetDefault("server.shutdown.pre_stop_delay", 0)
+	confReader.SetDefault("server.shutdown.timeout", "3s")
+	confReader.SetDefault("server.restart.enabled", false)
+	confReader.SetDefault("server.restart.signal", "SIGUSR2")
+	confReader.SetDefault("server.startup_check.timeout", "30s")
+	confReader.SetDefault("server.startup_check.backoff", "500ms")
+	confReader.SetDefault("server.startup_check.max_backoff", "5s")
+	confReader.SetDefault("management.port", 0) // 0 means disabled
+	confReader.SetDefault("management.base_path", "/actuator")
+	confReader.SetDefault("management.pprof", false)
 	confReader.AutomaticEnv()
-	if err := confReader.ReadInConfig(); err != nil {
-		logger.Log.Fatalf("Init project config error, %s", err.Error())
+	for _, source := range sources {
+		if err := source.Load(confReader); err != nil {
+			return fmt.Errorf("init project config from %s error, %w", source.Name(), err)
+		}
+	}
+	conf := &config{}
+	if err := confReader.Unmarshal(conf, decodeHook()); err != nil {
+		return fmt.Errorf("parse project config error, %w", err)
 	}
-	if err := confReader.Unmarshal(Conf); err != nil {
-		logger.Log.Fatalf("Parse project config error, %s", err.Error())
+	if err := conf.validate(); err != nil {
+		return fmt.Errorf("invalid project config, %w", err)
 	}
+	*Conf = *conf
 	ioc.SetBeans(confReader)
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name()
+	}
+	// the config is loaded, a failing listener must not fail the load
+	if err := event.Publish(context.Background(), event.ConfigChanged{Sources: names}); err != nil {
+		logger.Log.Errorf("publish config changed event error, %s", err.Error())
+	}
+	return nil
+}
+
+// decodeHook the decode hooks of the configuration, a bare number of a duration means seconds
+func decodeHook() viper.DecoderConfigOption {
+	return viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		secondsToDurationHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))
+}
+
+// UnmarshalKey decode the configuration block of the key into v, such as UnmarshalKey("timeout", &conf).
+// Use it rather than GetConfReader().UnmarshalKey so that a bare number of a duration means seconds as in the server block
+func UnmarshalKey(key string, v any) error {
+	return GetConfReader().UnmarshalKey(key, v, decodeHook())
+}
+
+// secondsToDurationHook decode a bare number as seconds, the default decoding treats it as nanoseconds
+func secondsToDurationHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(time.Duration(0)) {
+		return data, nil
+	}
+	switch from.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return time.Duration(reflect.ValueOf(data).Int()) * time.Second, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return time.Duration(reflect.ValueOf(data).Uint()) * time.Second, nil
+	case reflect.Float32, reflect.Float64:
+		return time.Duration(reflect.ValueOf(data).Float() * float64(time.Second)), nil
+	case reflect.String:
+		if seconds, err := strconv.ParseFloat(data.(string), 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+	}
+	return data, nil
 }
 
 // GetConfReader Get config reader of the application
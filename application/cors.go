@@ -0,0 +1,180 @@
+package application
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/internal/intercept"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// CorsPolicy the cross-origin resource sharing policy
+type CorsPolicy struct {
+	// AllowOrigins the allowed origins, such as https://example.com or https://*.example.com,
+	// "*" allows any origin and cannot be used with credentials. Empty means cross-origin requests are not allowed.
+	AllowOrigins     []string      `mapstructure:"allow_origins"`
+	AllowMethods     []string      `mapstructure:"allow_methods"`     // Default GET, POST, PUT, PATCH, DELETE, HEAD and OPTIONS
+	AllowHeaders     []string      `mapstructure:"allow_headers"`     // Default Origin, Content-Type and Authorization
+	ExposeHeaders    []string      `mapstructure:"expose_headers"`    // Response headers readable by the browser
+	AllowCredentials *bool         `mapstructure:"allow_credentials"` // Whether to allow cookies and the Authorization header, default false
+	MaxAge           time.Duration `mapstructure:"max_age"`           // How long the preflight result can be cached, default 12h
+}
+
+// CorsPathPolicy the policy of the paths, the unset fields inherit the global policy
+type CorsPathPolicy struct {
+	Path       string `mapstructure:"path"` // Request path, a trailing * matches the prefix such as /open/*
+	CorsPolicy `mapstructure:",squash"`
+}
+
+// CorsConfig the cors configuration, the paths are matched in order and the first one wins.
+// Example:
+//
+//	cors:
+//	  allow_origins: [https://*.example.com]
+//	  allow_credentials: true
+//	  paths:
+//	    - path: /open/*
+//	      allow_origins: ["*"]
+//	      allow_credentials: false
+type CorsConfig struct {
+	CorsPolicy `mapstructure:",squash"`
+	Paths      []CorsPathPolicy `mapstructure:"paths"`
+}
+
+// inherit fill the unset fields with the parent policy
+func (p CorsPolicy) inherit(parent CorsPolicy) CorsPolicy {
+	if len(p.AllowOrigins) == 0 {
+		p.AllowOrigins = parent.AllowOrigins
+	}
+	if len(p.AllowMethods) == 0 {
+		p.AllowMethods = parent.AllowMethods
+	}
+	if len(p.AllowHeaders) == 0 {
+		p.AllowHeaders = parent.AllowHeaders
+	}
+	if len(p.ExposeHeaders) == 0 {
+		p.ExposeHeaders = parent.ExposeHeaders
+	}
+	if p.AllowCredentials == nil {
+		p.AllowCredentials = parent.AllowCredentials
+	}
+	if p.MaxAge == 0 {
+		p.MaxAge = parent.MaxAge
+	}
+	return p
+}
+
+// corsConfig convert to the config of gin-contrib/cors, nil means cross-origin requests are not allowed
+func (p CorsPolicy) corsConfig() *cors.Config {
+	if len(p.AllowOrigins) == 0 {
+		return nil
+	}
+	conf := &cors.Config{
+		AllowMethods:     p.AllowMethods,
+		AllowHeaders:     p.AllowHeaders,
+		ExposeHeaders:    p.ExposeHeaders,
+		AllowCredentials: p.AllowCredentials != nil && *p.AllowCredentials,
+		MaxAge:           p.MaxAge,
+	}
+	if len(conf.AllowMethods) == 0 {
+		conf.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	}
+	if len(conf.AllowHeaders) == 0 {
+		conf.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	}
+	if conf.MaxAge == 0 {
+		conf.MaxAge = 12 * time.Hour
+	}
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			conf.AllowAllOrigins = true
+			return conf
+		}
+	}
+	origins := p.AllowOrigins
+	conf.AllowOriginFunc = func(origin string) bool {
+		for _, pattern := range origins {
+			if matchOrigin(pattern, origin) {
+				return true
+			}
+		}
+		return false
+	}
+	return conf
+}
+
+func (p CorsPolicy) validate(name string) error {
+	credentials := p.AllowCredentials != nil && *p.AllowCredentials
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" && credentials {
+			return fmt.Errorf("%s.allow_origins cannot be * when allow_credentials is true", name)
+		}
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("%s.allow_origins must start with http:// or https://, got %q", name, origin)
+		}
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("%s.max_age cannot be negative", name)
+	}
+	return nil
+}
+
+func (c *CorsConfig) validate() error {
+	if err := c.CorsPolicy.validate("cors"); err != nil {
+		return err
+	}
+	for i, p := range c.Paths {
+		if p.Path == "" {
+			return fmt.Errorf("cors.paths[%d].path is required", i)
+		}
+		if err := p.inherit(c.CorsPolicy).validate(fmt.Sprintf("cors.paths[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchOrigin match the origin with the pattern, * matches any characters such as https://*.example.com
+func matchOrigin(pattern, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return strings.EqualFold(pattern, origin)
+	}
+	origin = strings.ToLower(origin)
+	prefix, suffix = strings.ToLower(prefix), strings.ToLower(suffix)
+	return len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// newCorsMiddleware create the cors middleware from the configuration.
+// Requests from the disallowed origins are rejected with 403, when no origin is allowed the requests are served
+// without cors headers, so that browsers block them.
+func newCorsMiddleware(conf *CorsConfig) gin.HandlerFunc {
+	type pathHandler struct {
+		path    string
+		handler gin.HandlerFunc
+	}
+	build := func(p CorsPolicy) gin.HandlerFunc {
+		if c := p.corsConfig(); c != nil {
+			return cors.New(*c)
+		}
+		return nil
+	}
+	global := build(conf.CorsPolicy)
+	paths := make([]pathHandler, 0, len(conf.Paths))
+	for _, p := range conf.Paths {
+		paths = append(paths, pathHandler{path: p.Path, handler: build(p.inherit(conf.CorsPolicy))})
+	}
+	return func(ctx *gin.Context) {
+		handler := global
+		for _, p := range paths {
+			if intercept.MatchPath(p.path, ctx.Request.URL.Path) {
+				handler = p.handler
+				break
+			}
+		}
+		if handler != nil {
+			handler(ctx)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package application
+
+import (
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+)
+
+// SensitiveConfigKeys key fragments treated as secrets when dumping the configuration, case-insensitive
+var SensitiveConfigKeys = []string{"password", "passwd", "secret", "token", "credential", "private_key", "access_key", "api_key"}
+
+const maskedConfigValue = "******"
+
+// EffectiveConfig returns the merged configuration of all sources, secret values are masked
+func EffectiveConfig() map[string]any {
+	return maskConfig(GetConfReader().AllSettings())
+}
+
+// DumpConfig write the effective configuration to w in yaml format, secret values are masked.
+// It is useful to implement a command that prints the configuration.
+func DumpConfig(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(EffectiveConfig()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// ConfigDumpHandler respond the effective configuration, secret values are masked
+func ConfigDumpHandler(ctx *gin.Context) {
+	resp.Json(ctx, EffectiveConfig())
+}
+
+func maskConfig(settings map[string]any) map[string]any {
+	result := make(map[string]any, len(settings))
+	for k, v := range settings {
+		switch t := v.(type) {
+		case map[string]any:
+			result[k] = maskConfig(t)
+		default:
+			if isSensitiveKey(k) {
+				result[k] = maskedConfigValue
+				continue
+			}
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range SensitiveConfigKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"sort"
+	"time"
+)
+
+// Phase the lifecycle phase that a hook is attached to
+type Phase int
+
+const (
+	// PreApplyPhase before mvc starts, a failure aborts the startup
+	PreApplyPhase Phase = iota
+	// PreStartPhase after dependency injection is finished and before serving, a failure aborts the startup
+	PreStartPhase
+	// PreStopPhase before the servers are shut down, all hooks run even if some of them fail
+	PreStopPhase
+	// PostStopPhase after the servers are shut down, all hooks run even if some of them fail
+	PostStopPhase
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PreApplyPhase:
+		return "pre_apply"
+	case PreStartPhase:
+		return "pre_start"
+	case PreStopPhase:
+		return "pre_stop"
+	case PostStopPhase:
+		return "post_stop"
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// Hook a lifecycle hook
+type Hook struct {
+	Name    string                          // Name used in the report, default the phase and its index
+	Order   int                             // Lower runs first, hooks with the same order run in the order they are added
+	Timeout time.Duration                   // Maximum execution time, default 0 means no timeout
+	Func    func(ctx context.Context) error // The ctx is cancelled when the timeout is reached
+}
+
+// HookError the failure of a hook
+type HookError struct {
+	Phase    Phase
+	Hook     string
+	Elapsed  time.Duration
+	TimedOut bool
+	Err      error
+}
+
+func (h *HookError) Error() string {
+	if h.TimedOut {
+		return fmt.Sprintf("%s hook [%s] timed out after %s", h.Phase, h.Hook, h.Elapsed)
+	}
+	return fmt.Sprintf("%s hook [%s] failed after %s, %s", h.Phase, h.Hook, h.Elapsed, h.Err.Error())
+}
+
+func (h *HookError) Unwrap() error {
+	return h.Err
+}
+
+// Hook Add hooks to the lifecycle phase
+func (a *App) Hook(phase Phase, hooks ...Hook) *App {
+	if a.hooks == nil {
+		a.hooks = make(map[Phase][]Hook)
+	}
+	for _, hook := range hooks {
+		if hook.Func == nil {
+			logger.Log.Fatalf("%s hook func cannot be null.", phase)
+		}
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("%s#%d", phase, len(a.hooks[phase]))
+		}
+		a.hooks[phase] = append(a.hooks[phase], hook)
+	}
+	return a
+}
+
+// runHooks run the hooks of the phase in order.
+// Startup phases stop at the first failure, stop phases run all hooks and join the failures.
+func (a *App) runHooks(ctx context.Context, phase Phase) error {
+	hooks := make([]Hook, len(a.hooks[phase]))
+	copy(hooks, a.hooks[phase])
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].Order < hooks[j].Order
+	})
+	failFast := phase == PreApplyPhase || phase == PreStartPhase
+	var errs []error
+	for _, hook := range hooks {
+		start := time.Now()
+		err := runHook(ctx, hook)
+		elapsed := time.Since(start)
+		if err == nil {
+			logger.Log.Debugf("%s hook [%s] finished in %s", phase, hook.Name, elapsed)
+			continue
+		}
+		hookErr := &HookError{Phase: phase, Hook: hook.Name, Elapsed: elapsed, Err: err, TimedOut: errors.Is(err, context.DeadlineExceeded)}
+		if failFast {
+			return hookErr
+		}
+		logger.Log.Error(hookErr.Error())
+		errs = append(errs, hookErr)
+	}
+	return errors.Join(errs...)
+}
+
+// runHook run the hook and return when it finishes or the timeout is reached.
+// A hook ignoring the ctx keeps running in the background after the timeout.
+func runHook(ctx context.Context, hook Hook) (err error) {
+	if hook.Timeout <= 0 {
+		defer recoverHook(&err)
+		return hook.Func(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			done <- err
+		}()
+		defer recoverHook(&err)
+		err = hook.Func(ctx)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func recoverHook(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
+
+// wrapHook adapt the legacy func() hooks
+func wrapHook(f func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		f()
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/ioc"
+	"reflect"
+	"sort"
+)
+
+// Bean lifecycle interfaces, they are discovered automatically from the controllers, the beans injected into them
+// recursively and the beans added by App.Bean. Beans are invoked in dependency order, dependencies first.
+// The ioc container cannot be enumerated, so a bean added only by ioc.SetBeans and not injected into any
+// controller is never discovered, add it by App.Bean instead, which also works in a PreApply hook.
+
+// Initializer the bean is initialized after dependency injection is finished and before the server starts
+type Initializer interface {
+	Initialize(ctx context.Context) error
+}
+
+// SmartLifecycle the bean is started after all beans are initialized and stopped after the servers are shut down.
+// Beans start in ascending phase and stop in descending phase, the same phase follows the dependency order.
+type SmartLifecycle interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Phase() int
+}
+
+// Disposable the bean is destroyed after all lifecycle beans are stopped, dependents first
+type Disposable interface {
+	Destroy(ctx context.Context) error
+}
+
+// Bean Add beans to the ioc container and to the lifecycle discovery, they must be pointers.
+// Use it rather than ioc.SetBeans for the lifecycle beans that are not reachable from any controller.
+func (a *App) Bean(beans ...any) *App {
+	ioc.SetBeans(beans...)
+	a.beans = append(a.beans, beans...)
+	return a
+}
+
+// discoverBeans walk the exported pointer and interface fields from the roots,
+// the result is in dependency order because every bean is added after the beans it depends on.
+func discoverBeans(roots []any) []any {
+	var result []any
+	visited := make(map[uintptr]bool)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return
+		}
+		if visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		elem := v.Elem()
+		for i := 0; i < elem.NumField(); i++ {
+			if elem.Type().Field(i).IsExported() {
+				walk(elem.Field(i))
+			}
+		}
+		result = append(result, v.Interface())
+	}
+	for _, root := range roots {
+		walk(reflect.ValueOf(root))
+	}
+	return result
+}
+
+// startBeans initialize and start the discovered beans, the started ones are stopped if any of them fails
+func (a *App) startBeans(ctx context.Context) error {
+	a.lifecycleBeans = discoverBeans(append(append([]any{}, a.beans...), mvc.Controllers()...))
+	for _, bean := range a.lifecycleBeans {
+		if initializer, ok := bean.(Initializer); ok {
+			if err := initializer.Initialize(ctx); err != nil {
+				return fmt.Errorf("initialize bean %T error, %w", bean, err)
+			}
+		}
+	}
+	var lifecycles []SmartLifecycle
+	for _, bean := range a.lifecycleBeans {
+		if lifecycle, ok := bean.(SmartLifecycle); ok {
+			lifecycles = append(lifecycles, lifecycle)
+		}
+	}
+	sort.SliceStable(lifecycles, func(i, j int) bool {
+		return lifecycles[i].Phase() < lifecycles[j].Phase()
+	})
+	for _, lifecycle := range lifecycles {
+		if err := lifecycle.Start(ctx); err != nil {
+			err = fmt.Errorf("start bean %T error, %w", lifecycle, err)
+			return errors.Join(err, a.stopBeans(ctx))
+		}
+		a.startedBeans = append(a.startedBeans, lifecycle)
+		logger.Log.Debugf("bean %T started in phase %d", lifecycle, lifecycle.Phase())
+	}
+	return nil
+}
+
+// stopBeans stop the started beans in reverse order and then destroy the disposable beans, dependents first
+func (a *App) stopBeans(ctx context.Context) error {
+	var errs []error
+	for i := len(a.startedBeans) - 1; i >= 0; i-- {
+		if err := a.startedBeans[i].Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop bean %T error, %w", a.startedBeans[i], err))
+		}
+	}
+	a.startedBeans = nil
+	for i := len(a.lifecycleBeans) - 1; i >= 0; i-- {
+		if disposable, ok := a.lifecycleBeans[i].(Disposable); ok {
+			if err := disposable.Destroy(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("destroy bean %T error, %w", disposable, err))
+			}
+		}
+	}
+	a.lifecycleBeans = nil
+	return errors.Join(errs...)
+}
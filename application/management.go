@@ -0,0 +1,112 @@
+package application
+
+import (
+	"context"
+	"expvar"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HealthIndicator contribute to the health endpoint of the management server
+type HealthIndicator interface {
+	// Name of the indicator, used as the key of the health details
+	Name() string
+
+	// Health returns nil when healthy
+	Health(ctx context.Context) error
+}
+
+// Metrics of the main server, published through expvar
+var (
+	metricsOnce      sync.Once
+	requestsTotal    *expvar.Int
+	requestsInFlight *expvar.Int
+	responsesByCode  *expvar.Map
+)
+
+func initMetrics() {
+	metricsOnce.Do(func() {
+		requestsTotal = expvar.NewInt("gin_plus_requests_total")
+		requestsInFlight = expvar.NewInt("gin_plus_requests_in_flight")
+		responsesByCode = expvar.NewMap("gin_plus_responses")
+	})
+}
+
+// metricsMiddleware count the requests of the main server
+func metricsMiddleware(ctx *gin.Context) {
+	requestsTotal.Add(1)
+	requestsInFlight.Add(1)
+	defer func() {
+		requestsInFlight.Add(-1)
+		responsesByCode.Add(strconv.Itoa(ctx.Writer.Status()), 1)
+	}()
+	ctx.Next()
+}
+
+// newManagementServer build the management server, returns nil when it is disabled
+func (a *App) newManagementServer() *http.Server {
+	m := &Conf.Management
+	if m.Port <= 0 {
+		return nil
+	}
+	e := gin.New()
+	e.Use(gin.Recovery())
+	r := e.Group(m.BasePath)
+	r.GET("/health", a.healthHandler)
+	r.GET("/ready", a.readyHandler)
+	r.GET("/metrics", gin.WrapH(expvar.Handler()))
+	r.GET("/config", ConfigDumpHandler)
+	if m.Pprof {
+		r.GET("/debug/pprof/", gin.WrapF(pprof.Index))
+		r.GET("/debug/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+		r.GET("/debug/pprof/profile", gin.WrapF(pprof.Profile))
+		r.POST("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+		r.GET("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+		r.GET("/debug/pprof/trace", gin.WrapF(pprof.Trace))
+		r.GET("/debug/pprof/:name", func(ctx *gin.Context) {
+			pprof.Handler(ctx.Param("name")).ServeHTTP(ctx.Writer, ctx.Request)
+		})
+	}
+	for _, f := range a.managementRoutes {
+		f(r)
+	}
+	return &http.Server{
+		Addr:              net.JoinHostPort(m.Host, strconv.Itoa(m.Port)),
+		Handler:           e,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// healthHandler respond 200 when all indicators are healthy, otherwise 503 with the failure details
+func (a *App) healthHandler(ctx *gin.Context) {
+	details := make(map[string]string, len(a.healthIndicators))
+	healthy := true
+	for _, indicator := range a.healthIndicators {
+		if err := indicator.Health(ctx.Request.Context()); err != nil {
+			healthy = false
+			details[indicator.Name()] = err.Error()
+			continue
+		}
+		details[indicator.Name()] = "UP"
+	}
+	if healthy {
+		resp.InitResp(ctx, http.StatusOK).WithData(gin.H{"status": "UP", "details": details}).To()
+		return
+	}
+	resp.InitResp(ctx, http.StatusServiceUnavailable).WithCode(resp.SystemErrorCode).WithData(gin.H{"status": "DOWN", "details": details}).To()
+}
+
+// readyHandler respond 200 when the application is ready to serve, 503 when starting or shutting down
+func (a *App) readyHandler(ctx *gin.Context) {
+	if a.Ready() {
+		resp.InitResp(ctx, http.StatusOK).WithData(gin.H{"status": "READY"}).To()
+		return
+	}
+	resp.InitResp(ctx, http.StatusServiceUnavailable).WithCode(resp.SystemErrorCode).WithData(gin.H{"status": "NOT_READY"}).To()
+}
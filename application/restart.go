@@ -0,0 +1,88 @@
+package application
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Graceful restart passes the listening sockets to a child process through file descriptor inheritance.
+// The child serves on the inherited sockets and asks the parent to stop once it is started,
+// then the parent drains through the normal shutdown path. Only supported on unix.
+
+// inheritEnv lists the names of the inherited listeners, their file descriptors start at 3 in the same order
+const inheritEnv = "GIN_PLUS_INHERITED_LISTENERS"
+
+// parentEnv the pid of the process starting the graceful restart, the only process the child asks to stop
+const parentEnv = "GIN_PLUS_RESTART_PARENT"
+
+var (
+	inheritOnce sync.Once
+	inherited   map[string]net.Listener
+	inheritErr  error
+	parentPID   int
+)
+
+// restartChild the child process started by a graceful restart
+type restartChild struct {
+	takeover <-chan os.Signal // the child is serving and asks the parent to stop
+	exited   <-chan error     // the child exited before taking over
+	stop     func()
+}
+
+// inheritedListener returns the listener inherited from the parent process, nil when there is none
+func inheritedListener(name string) (net.Listener, error) {
+	inheritOnce.Do(func() {
+		names := os.Getenv(inheritEnv)
+		if names == "" {
+			return
+		}
+		parentPID, _ = strconv.Atoi(os.Getenv(parentEnv))
+		// the sub processes of this process must not inherit again
+		_ = os.Unsetenv(inheritEnv)
+		_ = os.Unsetenv(parentEnv)
+		inherited = make(map[string]net.Listener)
+		for i, n := range strings.Split(names, ",") {
+			f := os.NewFile(uintptr(3+i), n)
+			ln, err := net.FileListener(f)
+			_ = f.Close()
+			if err != nil {
+				inheritErr = fmt.Errorf("inherit listener %s error, %w", n, err)
+				return
+			}
+			inherited[n] = ln
+		}
+	})
+	if inheritErr != nil {
+		return nil, inheritErr
+	}
+	ln := inherited[name]
+	delete(inherited, name)
+	return ln, nil
+}
+
+// isInherited whether the process is started by a graceful restart
+func isInherited() bool {
+	return inherited != nil
+}
+
+// listen use the inherited listener of the name if present, otherwise listen a new one.
+// The listener is recorded so that it can be passed to the child process on restart.
+func (a *App) listen(name string, server *http.Server, unix string) (net.Listener, error) {
+	ln, err := inheritedListener(name)
+	if err != nil {
+		return nil, err
+	}
+	if ln == nil {
+		if ln, err = listen(server, unix); err != nil {
+			return nil, err
+		}
+	}
+	a.listenerNames = append(a.listenerNames, name)
+	a.listeners = append(a.listeners, ln)
+	return ln, nil
+}
@@ -0,0 +1,103 @@
+//go:build !windows
+
+package application
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var restartSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// notifyRestart returns the channel receiving the restart signal, nil when graceful restart is disabled
+func (a *App) notifyRestart() (<-chan os.Signal, func()) {
+	if !Conf.Server.Restart.Enabled {
+		return nil, func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, restartSignals[Conf.Server.Restart.Signal])
+	return ch, func() {
+		signal.Stop(ch)
+	}
+}
+
+// restart start a child process with the same executable, arguments and the listening sockets.
+// The parent keeps serving until the child asks it to stop with SIGTERM, the signal is caught
+// during the restart so that applications without signal handling are not killed by it.
+func (a *App) restart() (*restartChild, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*os.File, 0, len(a.listeners))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, ln := range a.listeners {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("listener %s cannot be inherited", ln.Addr().String())
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, inheritEnv+"=") && !strings.HasPrefix(e, parentEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(env, inheritEnv+"="+strings.Join(a.listenerNames, ","), parentEnv+"="+strconv.Itoa(os.Getpid()))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	takeover := make(chan os.Signal, 1)
+	signal.Notify(takeover, syscall.SIGTERM)
+	if err = cmd.Start(); err != nil {
+		signal.Stop(takeover)
+		return nil, err
+	}
+	// the child owns the unix socket file now, closing the listener here must not remove it
+	for _, ln := range a.listeners {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		if err == nil {
+			err = errors.New("exit status 0")
+		}
+		exited <- err
+	}()
+	return &restartChild{takeover: takeover, exited: exited, stop: func() { signal.Stop(takeover) }}, nil
+}
+
+// notifyParent ask the parent to shut down gracefully once the inherited listeners are serving.
+// Only the process starting the restart is signaled, it may have exited and the process be reparented.
+func notifyParent() error {
+	if !isInherited() {
+		return nil
+	}
+	if ppid := os.Getppid(); parentPID == 0 || ppid != parentPID {
+		return fmt.Errorf("the parent process %d is not the restarting process %d", ppid, parentPID)
+	}
+	return syscall.Kill(parentPID, syscall.SIGTERM)
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package application
+
+import (
+	"errors"
+	"os"
+)
+
+var restartSignals = map[string]os.Signal{}
+
+// notifyRestart graceful restart is not supported on windows
+func (a *App) notifyRestart() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
+
+func (a *App) restart() (*restartChild, error) {
+	return nil, errors.New("graceful restart is not supported on windows")
+}
+
+func notifyParent() error {
+	return nil
+}
@@ -0,0 +1,97 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// newServer build the http server from the server configuration
+func newServer() *http.Server {
+	return &http.Server{
+		Addr:                         net.JoinHostPort(Conf.Server.Host, strconv.Itoa(Conf.Server.Port)),
+		ReadTimeout:                  Conf.Server.ReadTimeout,
+		ReadHeaderTimeout:            Conf.Server.ReadHeaderTimeout,
+		WriteTimeout:                 Conf.Server.WriteTimeout,
+		IdleTimeout:                  Conf.Server.IdleTimeout,
+		MaxHeaderBytes:               Conf.Server.MaxHeaderBytes,
+		DisableGeneralOptionsHandler: true,
+	}
+}
+
+// listen on the unix socket when specified, otherwise on the tcp address of the server
+func listen(server *http.Server, unix string) (net.Listener, error) {
+	if unix == "" {
+		return net.Listen("tcp", server.Addr)
+	}
+	// remove the socket file left by the previous process
+	if err := os.Remove(unix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", unix)
+}
+
+// serve block until the server is closed
+func serve(server *http.Server, ln net.Listener) error {
+	if server.TLSConfig != nil {
+		// certificates are provided by the tls config
+		return server.ServeTLS(ln, "", "")
+	}
+	return server.Serve(ln)
+}
+
+// validate the server configuration at startup
+func (c *config) validate() error {
+	s := &c.Server
+	switch s.Env {
+	case Dev, Test, Prod:
+	default:
+		return fmt.Errorf("server.env must be one of dev, test and prod, got %q", s.Env)
+	}
+	if s.Unix == "" && (s.Port < 0 || s.Port > 65535) {
+		return fmt.Errorf("server.port must be between 0 and 65535, got %d", s.Port)
+	}
+	if s.MaxFileSize <= 0 {
+		return fmt.Errorf("server.max_file_size must be greater than 0, got %d", s.MaxFileSize)
+	}
+	if s.MaxHeaderBytes < 0 {
+		return fmt.Errorf("server.max_header_bytes cannot be negative, got %d", s.MaxHeaderBytes)
+	}
+	durations := map[string]int64{
+		"read_timeout":            int64(s.ReadTimeout),
+		"read_header_timeout":     int64(s.ReadHeaderTimeout),
+		"write_timeout":           int64(s.WriteTimeout),
+		"idle_timeout":            int64(s.IdleTimeout),
+		"shutdown.pre_stop_delay": int64(s.Shutdown.PreStopDelay),
+		"shutdown.timeout":        int64(s.Shutdown.Timeout),
+	}
+	positives := map[string]int64{
+		"startup_check.timeout":     int64(s.StartupCheck.Timeout),
+		"startup_check.backoff":     int64(s.StartupCheck.Backoff),
+		"startup_check.max_backoff": int64(s.StartupCheck.MaxBackoff),
+	}
+	for name, d := range positives {
+		if d <= 0 {
+			return fmt.Errorf("server.%s must be greater than 0", name)
+		}
+	}
+	for name, d := range durations {
+		if d < 0 {
+			return fmt.Errorf("server.%s cannot be negative", name)
+		}
+	}
+	if _, ok := restartSignals[s.Restart.Signal]; s.Restart.Enabled && !ok {
+		return fmt.Errorf("unsupported server.restart.signal %q on this platform", s.Restart.Signal)
+	}
+	m := &c.Management
+	if m.Port < 0 || m.Port > 65535 {
+		return fmt.Errorf("management.port must be between 0 and 65535, got %d", m.Port)
+	}
+	if m.Port > 0 && s.Unix == "" && m.Port == s.Port {
+		return fmt.Errorf("management.port cannot be the same as server.port %d", s.Port)
+	}
+	return c.Cors.validate()
+}
@@ -0,0 +1,144 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/event"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net"
+	"time"
+)
+
+// ShutdownReport the summary of a graceful shutdown
+type ShutdownReport struct {
+	PreStopDelay time.Duration // Time waited for load balancers after marking not ready
+	InFlight     int64         // In-flight requests when draining started
+	Cancelled    int64         // Requests still running when the drain timed out, their contexts were cancelled
+	DrainTime    time.Duration // Time spent draining in-flight requests
+	Elapsed      time.Duration // Total shutdown time
+	Errors       []error       // Failures of the shutdown stages
+}
+
+func (r *ShutdownReport) String() string {
+	return fmt.Sprintf("pre-stop delay %s, drained %d/%d in-flight requests in %s, cancelled %d, %d errors, total %s",
+		r.PreStopDelay, r.InFlight-r.Cancelled, r.InFlight, r.DrainTime, r.Cancelled, len(r.Errors), r.Elapsed)
+}
+
+// PreStopDelay Time to wait after marking the application not ready and before draining,
+// so that load balancers stop sending new requests. Default server.shutdown.pre_stop_delay
+func (a *App) PreStopDelay(delay time.Duration) *App {
+	a.preStopDelay = delay
+	return a
+}
+
+// Ready whether the application is started and not shutting down
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// LastShutdownReport the report of the last shutdown, nil before shutdown
+func (a *App) LastShutdownReport() *ShutdownReport {
+	return a.shutdownReport
+}
+
+// inFlightMiddleware count the in-flight requests of the main server
+func (a *App) inFlightMiddleware(ctx *gin.Context) {
+	a.inFlight.Add(1)
+	defer a.inFlight.Add(-1)
+	ctx.Next()
+}
+
+// initBaseContext make every request context derive from a context cancelled when the drain times out
+func (a *App) initBaseContext() {
+	a.baseCtx, a.cancelBase = context.WithCancel(context.Background())
+	a.server.BaseContext = func(net.Listener) context.Context {
+		return a.baseCtx
+	}
+}
+
+// Shutdown stop the application in stages:
+// mark not ready, run PreStop hooks, wait the pre-stop delay, drain in-flight requests, cancel the remaining
+// requests when the ctx is done, stop the management server, stop the beans and wait for the async event listeners
+// within ExitDelay, and finally run PostStop hooks.
+// The stop stages always run even if draining fails, the summary is logged and available from LastShutdownReport.
+func (a *App) Shutdown(ctx context.Context) error {
+	start := time.Now()
+	report := &ShutdownReport{PreStopDelay: a.preStopDelay}
+	a.shutdownReport = report
+	a.ready.Store(false)
+	logger.Log.Debug("Shutdown server ...")
+	if err := event.Publish(ctx, event.ShutdownStarted{}); err != nil {
+		report.Errors = append(report.Errors, err)
+	}
+	if err := a.runHooks(ctx, PreStopPhase); err != nil {
+		report.Errors = append(report.Errors, err)
+	}
+	if a.preStopDelay > 0 {
+		logger.Log.Debugf("Marked not ready, waiting %s for load balancers", a.preStopDelay)
+		select {
+		case <-time.After(a.preStopDelay):
+		case <-ctx.Done():
+		}
+	}
+	a.certReloader.Close()
+	report.InFlight = a.inFlight.Load()
+	drainStart := time.Now()
+	if err := a.drain(ctx); err != nil {
+		report.Cancelled = a.inFlight.Load()
+		report.Errors = append(report.Errors, err)
+	}
+	report.DrainTime = time.Since(drainStart)
+	// stop stages must run even if the ctx is done, the beans and the async event listeners
+	// share a budget of ExitDelay so that a hung task cannot block the exit
+	stopCtx := context.WithoutCancel(ctx)
+	beansCtx, cancelBeans := context.WithTimeout(stopCtx, a.exitDelay)
+	defer cancelBeans()
+	// the management server stops last, so that health and metrics stay observable while draining
+	if a.managementServer != nil {
+		if err := a.managementServer.Close(); err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("management server shutdown failure, %w", err))
+		}
+	}
+	if err := a.stopBeans(beansCtx); err != nil {
+		report.Errors = append(report.Errors, err)
+	}
+	if err := event.Default.Wait(beansCtx); err != nil {
+		report.Errors = append(report.Errors, fmt.Errorf("async event listeners not finished, %w", err))
+	}
+	if err := a.runHooks(stopCtx, PostStopPhase); err != nil {
+		report.Errors = append(report.Errors, err)
+	}
+	report.Elapsed = time.Since(start)
+	logger.Log.Infof("Shutdown summary: %s", report.String())
+	logger.Log.Debug("Server exiting ...")
+	return errors.Join(report.Errors...)
+}
+
+// drain wait for the in-flight requests, when the ctx is done the remaining request contexts are cancelled
+// and the connections are closed
+func (a *App) drain(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- a.server.Shutdown(ctx)
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				return nil
+			}
+			remaining := a.inFlight.Load()
+			if a.cancelBase != nil {
+				a.cancelBase()
+			}
+			_ = a.server.Close()
+			return fmt.Errorf("server shutdown failure, %d in-flight requests cancelled, %w", remaining, err)
+		case <-ticker.C:
+			logger.Log.Debugf("Draining, %d in-flight requests", a.inFlight.Load())
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Configuration sources, they are merged in the order they are added, the later one overrides the former.
+
+// FileSource read configuration from a file on disk, the format is determined by the file extension
+type FileSource struct {
+	Path     string
+	Optional bool // ignore the source when the file does not exist
+}
+
+func (f *FileSource) Name() string {
+	return "file:" + f.Path
+}
+
+func (f *FileSource) Load(reader *viper.Viper) error {
+	if f.Optional {
+		if _, err := os.Stat(f.Path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	reader.SetConfigFile(f.Path)
+	reader.SetConfigType(configType(f.Path))
+	return reader.MergeInConfig()
+}
+
+// ContentSource read configuration from memory, the format defaults to yaml
+type ContentSource struct {
+	Content []byte
+	Format  string
+}
+
+func (c *ContentSource) Name() string {
+	return "content"
+}
+
+func (c *ContentSource) Load(reader *viper.Viper) error {
+	return mergeContent(reader, c.Content, c.Format)
+}
+
+// EmbedSource read configuration from a file of the file system, usually an embed.FS
+// Example:
+//
+//	//go:embed app.yml
+//	var confFS embed.FS
+//
+//	application.DefaultWithConfig(application.WithConfigSource(&application.EmbedSource{FS: confFS, Path: "app.yml"}))
+type EmbedSource struct {
+	FS   fs.FS
+	Path string
+}
+
+func (e *EmbedSource) Name() string {
+	return "embed:" + e.Path
+}
+
+func (e *EmbedSource) Load(reader *viper.Viper) error {
+	content, err := fs.ReadFile(e.FS, e.Path)
+	if err != nil {
+		return err
+	}
+	return mergeContent(reader, content, configType(e.Path))
+}
+
+// DirSource read all configuration fragments of a directory in file name order, subdirectories are ignored.
+// When FS is nil, the directory is read from disk.
+type DirSource struct {
+	Dir string
+	FS  fs.FS
+}
+
+func (d *DirSource) Name() string {
+	return "dir:" + d.Dir
+}
+
+func (d *DirSource) Load(reader *viper.Viper) error {
+	fsys, dir := d.FS, d.Dir
+	if fsys == nil {
+		fsys, dir = os.DirFS(d.Dir), "."
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(viper.SupportedExts, configType(entry.Name())) {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err = mergeContent(reader, content, configType(entry.Name())); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// EnvSource map environment variables with the prefix onto the known configuration keys.
+// The variable name is the prefix and the upper case key joined by "_", such as APP_SERVER_MAX_FILE_SIZE for server.max_file_size.
+// Only keys that already exist in the former sources or defaults are mapped, so put it after them.
+type EnvSource struct {
+	Prefix string
+}
+
+func (e *EnvSource) Name() string {
+	return "env:" + e.Prefix
+}
+
+func (e *EnvSource) Load(reader *viper.Viper) error {
+	values := make(map[string]any)
+	for _, key := range reader.AllKeys() {
+		name := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if e.Prefix != "" {
+			name = strings.ToUpper(e.Prefix) + "_" + name
+		}
+		if val, ok := os.LookupEnv(name); ok {
+			setNested(values, strings.Split(key, "."), val)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return reader.MergeConfigMap(values)
+}
+
+// RemoteProvider fetch the configuration content from a remote system, such as a configuration center
+type RemoteProvider interface {
+	// Fetch returns the configuration content and its format, the format is a viper supported extension such as yaml
+	Fetch(ctx context.Context) (content []byte, format string, err error)
+}
+
+// RemoteSource read configuration from a remote provider
+type RemoteSource struct {
+	Provider RemoteProvider
+	Timeout  time.Duration // fetch timeout, default 5s
+	Optional bool          // ignore the source when the fetch fails
+}
+
+func (r *RemoteSource) Name() string {
+	if n, ok := r.Provider.(interface{ Name() string }); ok {
+		return "remote:" + n.Name()
+	}
+	return fmt.Sprintf("remote:%T", r.Provider)
+}
+
+func (r *RemoteSource) Load(reader *viper.Viper) error {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	content, format, err := r.Provider.Fetch(ctx)
+	if err != nil {
+		if r.Optional {
+			return nil
+		}
+		return err
+	}
+	return mergeContent(reader, content, format)
+}
+
+// HTTPProvider fetch the configuration with a GET request, the format defaults to the extension of the url
+type HTTPProvider struct {
+	URL    string
+	Format string
+	Header http.Header
+	Client *http.Client
+}
+
+func (h *HTTPProvider) Name() string {
+	return h.URL
+}
+
+func (h *HTTPProvider) Fetch(ctx context.Context) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", res.Status)
+	}
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	format := h.Format
+	if format == "" {
+		format = configType(req.URL.Path)
+	}
+	return content, format, nil
+}
+
+func configType(file string) string {
+	return strings.TrimPrefix(filepath.Ext(file), ".")
+}
+
+func mergeContent(reader *viper.Viper, content []byte, format string) error {
+	if format == "" {
+		format = "yaml"
+	}
+	reader.SetConfigType(format)
+	return reader.MergeConfig(bytes.NewReader(content))
+}
+
+func setNested(m map[string]any, keys []string, val any) {
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = val
+}
@@ -0,0 +1,114 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StartupCheck the bean checks a dependency before the application serves, such as pinging the database.
+// Checks are discovered in the same way as lifecycle beans or added by App.StartupCheck,
+// they run concurrently and are retried with backoff until they pass or server.startup_check.timeout is reached.
+// Implement Name() string to customize the name in the report.
+type StartupCheck interface {
+	Check(ctx context.Context) error
+}
+
+// StartupCheckResult the result of a startup check
+type StartupCheckResult struct {
+	Name     string
+	Attempts int
+	Elapsed  time.Duration
+	Err      error // the last error, nil when passed
+}
+
+// StartupCheckError the aggregated failure of startup checks
+type StartupCheckError struct {
+	Results []StartupCheckResult
+}
+
+func (s *StartupCheckError) Error() string {
+	var b strings.Builder
+	b.WriteString("startup checks failed:")
+	for _, r := range s.Results {
+		status := "PASSED"
+		if r.Err != nil {
+			status = "FAILED, " + r.Err.Error()
+		}
+		b.WriteString(fmt.Sprintf("\n  - [%s] %d attempts in %s, %s", r.Name, r.Attempts, r.Elapsed.Round(time.Millisecond), status))
+	}
+	return b.String()
+}
+
+// StartupCheck Add checks that must pass before the server starts listening
+func (a *App) StartupCheck(checks ...StartupCheck) *App {
+	a.startupChecks = append(a.startupChecks, checks...)
+	return a
+}
+
+// runStartupChecks run all checks concurrently, it returns a StartupCheckError when any of them does not pass in time
+func (a *App) runStartupChecks(ctx context.Context) error {
+	checks := append([]StartupCheck{}, a.startupChecks...)
+	for _, bean := range a.lifecycleBeans {
+		if check, ok := bean.(StartupCheck); ok {
+			checks = append(checks, check)
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+	conf := &Conf.Server.StartupCheck
+	ctx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	defer cancel()
+	results := make([]StartupCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check StartupCheck) {
+			defer wg.Done()
+			results[i] = runStartupCheck(ctx, check, conf.Backoff, conf.MaxBackoff)
+		}(i, check)
+	}
+	wg.Wait()
+	for _, r := range results {
+		if r.Err != nil {
+			return &StartupCheckError{Results: results}
+		}
+	}
+	for _, r := range results {
+		logger.Log.Debugf("startup check [%s] passed after %d attempts in %s", r.Name, r.Attempts, r.Elapsed)
+	}
+	return nil
+}
+
+// runStartupCheck retry the check with exponential backoff until it passes or the ctx is done
+func runStartupCheck(ctx context.Context, check StartupCheck, backoff, maxBackoff time.Duration) (result StartupCheckResult) {
+	result.Name = fmt.Sprintf("%T", check)
+	if n, ok := check.(interface{ Name() string }); ok {
+		result.Name = n.Name()
+	}
+	start := time.Now()
+	defer func() {
+		result.Elapsed = time.Since(start)
+	}()
+	for {
+		result.Attempts++
+		if result.Err = check.Check(ctx); result.Err == nil {
+			return result
+		}
+		logger.Log.Debugf("startup check [%s] attempt %d failed, %s", result.Name, result.Attempts, result.Err.Error())
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package application
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/archine/gin-plus/v3/internal/filewatch"
+	"os"
+	"sync"
+)
+
+// TLSConfig the server tls configuration, tls is enabled when the certificate file is set
+type TLSConfig struct {
+	CertFile     string `mapstructure:"cert_file"`      // Certificate file in PEM format
+	KeyFile      string `mapstructure:"key_file"`       // Private key file in PEM format
+	MinVersion   string `mapstructure:"min_version"`    // Minimum tls version, default 1.2, supports 1.0, 1.1, 1.2 and 1.3
+	ClientAuth   string `mapstructure:"client_auth"`    // Client authentication, default none, supports none, request, require, verify_if_given and require_and_verify
+	ClientCAFile string `mapstructure:"client_ca_file"` // CA bundle used to verify client certificates, required for mTLS
+	Reload       bool   `mapstructure:"reload"`         // Reload the certificate automatically when the files change
+}
+
+// Enabled whether tls is configured
+func (t *TLSConfig) Enabled() bool {
+	return t.CertFile != ""
+}
+
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsClientAuths = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// buildTLSConfig convert the configuration to the tls.Config of the http server.
+// The returned reloader is nil when reload is disabled, it must be closed when the server stops.
+func buildTLSConfig(c *TLSConfig) (*tls.Config, *certReloader, error) {
+	if c.KeyFile == "" {
+		return nil, nil, fmt.Errorf("server.tls.key_file is required when cert_file is set")
+	}
+	minVersion, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported server.tls.min_version %q", c.MinVersion)
+	}
+	clientAuth, ok := tlsClientAuths[c.ClientAuth]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported server.tls.client_auth %q", c.ClientAuth)
+	}
+	reloader := &certReloader{certFile: c.CertFile, keyFile: c.KeyFile}
+	if err := reloader.load(); err != nil {
+		return nil, nil, err
+	}
+	tlsConf := &tls.Config{
+		MinVersion:     minVersion,
+		ClientAuth:     clientAuth,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: reloader.getCertificate,
+	}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read client ca file error, %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no valid certificate found in client ca file %s", c.ClientCAFile)
+		}
+		tlsConf.ClientCAs = pool
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, nil, fmt.Errorf("server.tls.client_ca_file is required when client_auth is %s", c.ClientAuth)
+	}
+	if !c.Reload {
+		return tlsConf, nil, nil
+	}
+	if err := reloader.watch(); err != nil {
+		return nil, nil, err
+	}
+	return tlsConf, reloader, nil
+}
+
+// certReloader hold the current certificate and reload it when the files change
+type certReloader struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	watcher  *filewatch.Watcher
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls certificate error, %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch reload the certificate when the files change
+func (r *certReloader) watch() error {
+	watcher, err := filewatch.Watch("tls certificate", []string{r.certFile, r.keyFile}, r.load)
+	if err != nil {
+		return err
+	}
+	r.watcher = watcher
+	return nil
+}
+
+func (r *certReloader) Close() {
+	if r != nil && r.watcher != nil {
+		_ = r.watcher.Close()
+	}
+}
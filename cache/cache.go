@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/archine/gin-plus/v3/internal/intercept"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Response caching of the GET apis annotated with @Cacheable.
+// Example:
+//
+//	c := cache.New(cache.Config{})
+//	app.Interceptor(c).Bean(c)
+//
+//	// Get
+//	// @GET(path="/user/:id")
+//	// @Cacheable(ttl="5m", key="user:{id}", tags=[users], vary=[Accept-Language])
+//	func (u *UserController) Get(ctx *gin.Context)
+//
+//	// after the user is updated, all the variants of the key are evicted
+//	_ = c.Evict(ctx, "user:42")
+
+// AnnotationName the annotation declaring the response of the api cacheable
+const AnnotationName = "Cacheable"
+
+// StatusHeader the header telling whether the response is served from the cache, HIT or MISS
+const StatusHeader = "X-Cache"
+
+// recorderKey the key of the response recorder in the gin context
+const recorderKey = "gin-plus/cache/recorder"
+
+// Config the response cache configuration, read it from app.yml with application.UnmarshalKey so that a bare TTL is seconds
+type Config struct {
+	TTL  time.Duration `mapstructure:"ttl"`  // Default lifetime of the cached responses, default 1m
+	Vary []string      `mapstructure:"vary"` // Request headers varying the responses of all apis, such as Accept-Language
+}
+
+// Entry the cached response
+type Entry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	ETag   string
+}
+
+// Cache keep the responses by key and variant, the variant is made of the values of the vary headers.
+// The responses are per instance with the in-memory cache, implement it with redis or memcached to share them
+type Cache interface {
+	// Get returns nil when the variant of the key is absent or expired
+	Get(ctx context.Context, key, variant string) (*Entry, error)
+	Set(ctx context.Context, key, variant string, entry *Entry, ttl time.Duration, tags []string) error
+	// Delete all the variants of the keys
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteTags delete all the keys with any of the tags
+	DeleteTags(ctx context.Context, tags ...string) error
+}
+
+// annotation the parameters of @Cacheable
+type annotation struct {
+	TTL  time.Duration `anno:"ttl"`  // Overrides Config.TTL
+	Key  string        `anno:"key"`  // Custom key, {name} is replaced by the path parameter or the query, default the path and the query
+	Tags []string      `anno:"tags"` // Tags for eviction, {name} is replaced in the same way as the key
+	Vary []string      `anno:"vary"` // Request headers varying the response, use Authorization for the responses of each user
+}
+
+// Interceptor the response cache interceptor, only successful GET responses without cookies are cached.
+// The response of a miss is buffered to compute its ETag, so do not declare @Cacheable on streaming apis.
+// Add it after the security interceptor so that unauthorized requests never reach the cache.
+type Interceptor struct {
+	conf  Config
+	cache Cache
+}
+
+// New Create the response cache interceptor with the in-memory cache
+func New(conf Config) *Interceptor {
+	if conf.TTL <= 0 {
+		conf.TTL = time.Minute
+	}
+	return &Interceptor{conf: conf, cache: NewMemoryCache(10000)}
+}
+
+// Cache Sets the cache of the responses
+func (i *Interceptor) Cache(cache Cache) *Interceptor {
+	i.cache = cache
+	return i
+}
+
+// Evict delete the cached responses by the keys with all their variants, the key is the one declared
+// by the annotation or the path and the query such as /user?page=1
+func (i *Interceptor) Evict(ctx context.Context, keys ...string) error {
+	return i.cache.Delete(ctx, keys...)
+}
+
+// EvictTags delete the cached responses with any of the tags
+func (i *Interceptor) EvictTags(ctx context.Context, tags ...string) error {
+	return i.cache.DeleteTags(ctx, tags...)
+}
+
+func (i *Interceptor) Predicate(ctx *gin.Context) bool {
+	if ctx.Request.Method != http.MethodGet {
+		return false
+	}
+	_, ok := mvc.GetAnnotation(ctx, AnnotationName)
+	return ok
+}
+
+func (i *Interceptor) PreHandle(ctx *gin.Context) {
+	var anno annotation
+	if err := mvc.GetAnnotations(ctx, AnnotationName)[0].Decode(&anno); err != nil {
+		logger.Log.Errorf("invalid @%s of %s, %s", AnnotationName, ctx.FullPath(), err.Error())
+		return
+	}
+	key, variant := i.key(ctx, anno), i.variant(ctx, anno)
+	entry, err := i.cache.Get(ctx, key, variant)
+	if err != nil {
+		// serve without the cache
+		logger.Log.Errorf("response cache error, %s", err.Error())
+		return
+	}
+	if entry != nil {
+		header := ctx.Writer.Header()
+		for k, v := range entry.Header {
+			header[k] = v
+		}
+		header.Set("ETag", entry.ETag)
+		header.Set(StatusHeader, "HIT")
+		if matchETag(ctx.GetHeader("If-None-Match"), entry.ETag) {
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		ctx.Status(entry.Status)
+		_, _ = ctx.Writer.Write(entry.Body)
+		ctx.Abort()
+		return
+	}
+	ttl := anno.TTL
+	if ttl <= 0 {
+		ttl = i.conf.TTL
+	}
+	rec := &recorder{Recorder: intercept.NewBufferedRecorder(ctx.Writer), key: key, variant: variant, ttl: ttl, tags: interpolateAll(ctx, anno.Tags)}
+	ctx.Writer = rec.Recorder
+	ctx.Set(recorderKey, rec)
+}
+
+func (i *Interceptor) PostHandle(ctx *gin.Context) {
+	if v, ok := ctx.Get(recorderKey); ok {
+		v.(*recorder).completed = true
+	}
+}
+
+// AfterCompletion write the buffered response with its ETag, and cache it when the method completed successfully
+func (i *Interceptor) AfterCompletion(ctx *gin.Context) {
+	v, ok := ctx.Get(recorderKey)
+	if !ok {
+		return
+	}
+	rec := v.(*recorder)
+	// the exception interceptor writes the error of a panic after this, directly to the client
+	ctx.Writer = rec.ResponseWriter
+	body := rec.Body()
+	tag := etag(body)
+	header := rec.Header()
+	header.Set(StatusHeader, "MISS")
+	store := rec.completed && cacheable(ctx, rec)
+	if store {
+		header.Set("ETag", tag)
+		entryHeader := header.Clone()
+		for _, h := range []string{"Date", StatusHeader, "ETag"} {
+			entryHeader.Del(h)
+		}
+		entry := &Entry{Status: rec.Status(), Header: entryHeader, Body: body, ETag: tag}
+		// store a detached context, the request context may be cancelled when the client goes away
+		if err := i.cache.Set(context.WithoutCancel(ctx.Request.Context()), rec.key, rec.variant, entry, rec.ttl, rec.tags); err != nil {
+			logger.Log.Errorf("response cache error, %s", err.Error())
+		}
+	}
+	rec.Commit()
+}
+
+// key the declared key, or the path and the sorted query
+func (i *Interceptor) key(ctx *gin.Context, anno annotation) string {
+	if anno.Key != "" {
+		return interpolate(ctx, anno.Key)
+	}
+	key := ctx.Request.URL.Path
+	if query := ctx.Request.URL.Query(); len(query) > 0 {
+		// Encode sorts the query by key
+		key += "?" + query.Encode()
+	}
+	return key
+}
+
+// variant the values of the vary headers
+func (i *Interceptor) variant(ctx *gin.Context, anno annotation) string {
+	vary := append(append([]string{}, i.conf.Vary...), anno.Vary...)
+	sort.Strings(vary)
+	var b strings.Builder
+	for _, h := range vary {
+		b.WriteString(h + "=" + ctx.GetHeader(h) + "|")
+	}
+	return b.String()
+}
+
+// cacheable only successful responses without cookies and not declared private are cached
+func cacheable(ctx *gin.Context, rec *recorder) bool {
+	if rec.Status() != http.StatusOK || ctx.GetInt("bcode") != 0 || ctx.IsAborted() {
+		return false
+	}
+	header := rec.Header()
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	control := strings.ToLower(header.Get("Cache-Control"))
+	return !strings.Contains(control, "no-store") && !strings.Contains(control, "private")
+}
+
+// interpolate replace {name} with the path parameter or the query
+func interpolate(ctx *gin.Context, s string) string {
+	for {
+		start := strings.IndexByte(s, '{')
+		end := strings.IndexByte(s, '}')
+		if start < 0 || end < start {
+			return s
+		}
+		name := s[start+1 : end]
+		val := ctx.Param(name)
+		if val == "" {
+			val = ctx.Query(name)
+		}
+		s = s[:start] + val + s[end+1:]
+	}
+}
+
+func interpolateAll(ctx *gin.Context, values []string) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = interpolate(ctx, v)
+	}
+	return result
+}
+
+// etag the strong entity tag of the body
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag whether the If-None-Match header matches the entity tag, weak comparison is used as RFC 9110 requires
+func matchETag(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder the buffered response of a miss and where to cache it
+type recorder struct {
+	*intercept.Recorder
+	completed bool // the method completed without panic
+	key       string
+	variant   string
+	ttl       time.Duration
+	tags      []string
+}
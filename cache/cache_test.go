@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/cache"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+)
+
+type UserController struct {
+	mvc.Controller
+	calls int
+}
+
+func (u *UserController) Get(ctx *gin.Context) {
+	u.calls++
+	resp.Json(ctx, u.calls)
+}
+
+func (u *UserController) Fail(ctx *gin.Context) {
+	u.calls++
+	panic(errors.New("database is down"))
+}
+
+func newHarness(t *testing.T, c *UserController, i *cache.Interceptor) *gintest.Harness {
+	core.Apis = map[string][]*core.MethodInfo{
+		"UserController": {
+			{Method: http.MethodGet, ApiPath: "/user/:id", Name: "Get", Annotations: map[string]string{cache.AnnotationName: `(key="user:{id}", vary=[Accept-Language])`}},
+			{Method: http.MethodGet, ApiPath: "/fail", Name: "Fail", Annotations: map[string]string{cache.AnnotationName: ""}},
+		},
+	}
+	return gintest.New(t, gintest.WithController(c), gintest.WithApp(func(app *application.App) {
+		app.Interceptor(i)
+	}))
+}
+
+func get(h *gintest.Harness, path, lang, ifNoneMatch string) *gintest.Response {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept-Language", lang)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	return h.Client().Do(req)
+}
+
+func TestETag(t *testing.T) {
+	h := newHarness(t, &UserController{}, cache.New(cache.Config{}))
+	miss := get(h, "/user/1", "en", "").AssertStatus(http.StatusOK).AssertHeader(cache.StatusHeader, "MISS")
+	tag := miss.Recorder.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("expected the ETag of the missed response")
+	}
+	get(h, "/user/1", "en", "").AssertHeader(cache.StatusHeader, "HIT").AssertHeader("ETag", tag)
+	if got := get(h, "/user/1", "en", "W/"+tag).Recorder.Code; got != http.StatusNotModified {
+		t.Errorf("expected http status 304, got %d", got)
+	}
+}
+
+func TestEvictVariants(t *testing.T) {
+	c := &UserController{}
+	i := cache.New(cache.Config{})
+	h := newHarness(t, c, i)
+	get(h, "/user/1", "en", "").AssertHeader(cache.StatusHeader, "MISS")
+	get(h, "/user/1", "zh", "").AssertHeader(cache.StatusHeader, "MISS")
+	get(h, "/user/1", "zh", "").AssertHeader(cache.StatusHeader, "HIT")
+	if err := i.Evict(context.Background(), "user:1"); err != nil {
+		t.Fatal(err)
+	}
+	get(h, "/user/1", "en", "").AssertHeader(cache.StatusHeader, "MISS")
+	get(h, "/user/1", "zh", "").AssertHeader(cache.StatusHeader, "MISS")
+	if c.calls != 4 {
+		t.Errorf("expected 4 calls, got %d", c.calls)
+	}
+}
+
+func TestPanicNotCached(t *testing.T) {
+	c := &UserController{}
+	h := newHarness(t, c, cache.New(cache.Config{}))
+	get(h, "/fail", "", "").AssertCode(resp.SystemErrorCode)
+	get(h, "/fail", "", "").AssertCode(resp.SystemErrorCode).AssertHeader(cache.StatusHeader, "MISS")
+	if c.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", c.calls)
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache the in-memory cache, the entries are per instance
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	size       int
+	entries    map[string]map[string]*memoryEntry // key -> variant -> entry
+	tags       map[string]map[string]struct{}     // tag -> keys
+	keyTags    map[string][]string                // key -> tags
+}
+
+type memoryEntry struct {
+	entry    *Entry
+	expireAt time.Time
+}
+
+// NewMemoryCache Create an in-memory cache holding at most maxEntries responses,
+// new responses are not cached when it is full of unexpired entries
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]map[string]*memoryEntry),
+		tags:       make(map[string]map[string]struct{}),
+		keyTags:    make(map[string][]string),
+	}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key, variant string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key][variant]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(e.expireAt) {
+		m.deleteVariant(key, variant)
+		return nil, nil
+	}
+	return e.entry, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key, variant string, entry *Entry, ttl time.Duration, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteVariant(key, variant)
+	if m.size >= m.maxEntries {
+		now := time.Now()
+		for k, variants := range m.entries {
+			for v, e := range variants {
+				if now.After(e.expireAt) {
+					m.deleteVariant(k, v)
+				}
+			}
+		}
+		if m.size >= m.maxEntries {
+			return nil
+		}
+	}
+	variants, ok := m.entries[key]
+	if !ok {
+		variants = make(map[string]*memoryEntry)
+		m.entries[key] = variants
+	}
+	variants[variant] = &memoryEntry{entry: entry, expireAt: time.Now().Add(ttl)}
+	m.size++
+	for _, tag := range tags {
+		keys, ok := m.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		if _, ok = keys[key]; !ok {
+			keys[key] = struct{}{}
+			m.keyTags[key] = append(m.keyTags[key], tag)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		m.delete(key)
+	}
+	return nil
+}
+
+func (m *MemoryCache) DeleteTags(ctx context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			m.delete(key)
+		}
+	}
+	return nil
+}
+
+// deleteVariant delete a variant of the key, the key is deleted with its last variant, the lock must be held
+func (m *MemoryCache) deleteVariant(key, variant string) {
+	variants := m.entries[key]
+	if _, ok := variants[variant]; !ok {
+		return
+	}
+	delete(variants, variant)
+	m.size--
+	if len(variants) == 0 {
+		m.delete(key)
+	}
+}
+
+// delete all the variants of the key and its tag index, the lock must be held
+func (m *MemoryCache) delete(key string) {
+	m.size -= len(m.entries[key])
+	delete(m.entries, key)
+	for _, tag := range m.keyTags[key] {
+		delete(m.tags[tag], key)
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+	delete(m.keyTags, key)
+}
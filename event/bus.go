@@ -0,0 +1,166 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// In-process application event bus.
+// Example:
+//
+//	event.On(func(ctx context.Context, e event.ApplicationStarted) error {
+//	    logger.Log.Infof("started on %s", e.Addr)
+//	    return nil
+//	})
+//
+//	event.Publish(ctx, UserCreated{ID: 1})
+
+// Default the bus used by the package level functions and the built-in events
+var Default = NewBus()
+
+type listener struct {
+	id        uint64
+	eventType reflect.Type
+	order     int
+	async     bool
+	handle    func(ctx context.Context, e any) error
+}
+
+// Option customize a listener
+type Option func(l *listener)
+
+// Async run the listener in a new goroutine, its error and panic are logged instead of returned
+func Async() Option {
+	return func(l *listener) {
+		l.async = true
+	}
+}
+
+// Order lower runs first, listeners with the same order run in the order they are subscribed
+func Order(order int) Option {
+	return func(l *listener) {
+		l.order = order
+	}
+}
+
+// Bus dispatch events to the listeners subscribed to their types
+type Bus struct {
+	mu        sync.RWMutex
+	nextID    uint64
+	listeners []*listener
+	wg        sync.WaitGroup
+}
+
+// NewBus create an empty bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe add a listener of the events assignable to T, it returns a function removing the listener.
+// When T is an interface, all events implementing it are received.
+func Subscribe[T any](b *Bus, f func(ctx context.Context, e T) error, options ...Option) (unsubscribe func()) {
+	l := &listener{
+		eventType: reflect.TypeOf((*T)(nil)).Elem(),
+		handle: func(ctx context.Context, e any) error {
+			return f(ctx, e.(T))
+		},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	b.mu.Lock()
+	b.nextID++
+	l.id = b.nextID
+	b.listeners = append(b.listeners, l)
+	sort.SliceStable(b.listeners, func(i, j int) bool {
+		return b.listeners[i].order < b.listeners[j].order
+	})
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, cached := range b.listeners {
+			if cached.id == l.id {
+				b.listeners = append(b.listeners[:i], b.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish dispatch the event to the listeners in order.
+// Synchronous listeners run in the caller goroutine, their errors and panics are joined and returned
+// after all of them have run.
+func (b *Bus) Publish(ctx context.Context, e any) error {
+	if e == nil {
+		return nil
+	}
+	eventType := reflect.TypeOf(e)
+	b.mu.RLock()
+	var matched []*listener
+	for _, l := range b.listeners {
+		if eventType.AssignableTo(l.eventType) {
+			matched = append(matched, l)
+		}
+	}
+	b.mu.RUnlock()
+	var errs []error
+	for _, l := range matched {
+		if l.async {
+			b.wg.Add(1)
+			go func(l *listener) {
+				defer b.wg.Done()
+				if err := dispatch(context.WithoutCancel(ctx), l, e); err != nil {
+					logger.Log.Errorf("Async listener of %T failed, %s", e, err.Error())
+				}
+			}(l)
+			continue
+		}
+		if err := dispatch(ctx, l, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Wait block until the running async listeners finish or the ctx is done
+func (b *Bus) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch call the listener, the panic is reported through the exception package and returned as an error
+func dispatch(ctx context.Context, l *listener, e any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			exception.Report(r)
+			err = fmt.Errorf("listener of %T panic, %v", e, r)
+		}
+	}()
+	return l.handle(ctx, e)
+}
+
+// On subscribe a listener to the default bus
+func On[T any](f func(ctx context.Context, e T) error, options ...Option) (unsubscribe func()) {
+	return Subscribe(Default, f, options...)
+}
+
+// Publish dispatch the event with the default bus
+func Publish(ctx context.Context, e any) error {
+	return Default.Publish(ctx, e)
+}
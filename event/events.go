@@ -0,0 +1,27 @@
+package event
+
+import "net"
+
+// Built-in events published by the framework on the default bus
+
+// ApplicationStarted published when the application is serving
+type ApplicationStarted struct {
+	Addr           net.Addr // Address of the main server
+	ManagementAddr net.Addr // Address of the management server, nil when it is disabled
+}
+
+// ConfigChanged published whenever the application configuration is loaded
+type ConfigChanged struct {
+	Sources []string // Names of the loaded configuration sources in order
+}
+
+// ShutdownStarted published when the application starts shutting down, before the PreStop hooks
+type ShutdownStarted struct{}
+
+// RouteRegistered published for each api registered by mvc.Apply
+type RouteRegistered struct {
+	Method     string // HTTP method such as GET
+	Path       string // Full path of the api
+	Controller string // Name of the controller struct
+	Handler    string // Name of the controller method
+}
@@ -61,6 +61,19 @@ func GlobalExceptionInterceptor(context *gin.Context) {
 	context.Next()
 }
 
+// Report log a value recovered outside of a request, such as in background tasks,
+// in the same way as GlobalExceptionInterceptor
+func Report(r any) {
+	switch t := r.(type) {
+	case *BusinessException:
+		printSimpleStack(t.msg)
+	case error:
+		printStack(t)
+	default:
+		logger.Log.Error(r)
+	}
+}
+
 // OrThrow if err not nil, panic
 func OrThrow(err error) {
 	if err != nil {
@@ -0,0 +1,310 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Managed executor with bounded worker pools, use it instead of spawning goroutines in handlers.
+// Example:
+//
+//	exec := executor.New(executor.PoolConfig{Name: "mail", Workers: 4, QueueSize: 100, Reject: executor.CallerRuns})
+//	application.Default().Bean(exec).Run()
+//
+//	// in the controller, the task still sees the values of the request context after the response is written
+//	t.Executor.Pool("mail").Submit(ctx, func(ctx context.Context) error { ... })
+
+// DefaultPool the name of the pool used by Executor.Submit
+const DefaultPool = "default"
+
+var (
+	// ErrRejected the queue of the pool is full
+	ErrRejected = errors.New("task rejected, the queue is full")
+	// ErrShutdown the pool is shut down
+	ErrShutdown = errors.New("task rejected, the pool is shut down")
+)
+
+// Task the asynchronous work, the ctx carries the values of the submitting context and is cancelled
+// only when the drain times out during shutdown
+type Task func(ctx context.Context) error
+
+// RejectPolicy how to handle a task when the queue is full
+type RejectPolicy int
+
+const (
+	// Abort return ErrRejected to the caller
+	Abort RejectPolicy = iota
+	// CallerRuns run the task in the goroutine of the caller
+	CallerRuns
+	// Discard drop the task silently
+	Discard
+	// DiscardOldest drop the oldest queued task and enqueue the new one
+	DiscardOldest
+)
+
+// PoolConfig the pool configuration
+type PoolConfig struct {
+	Name      string
+	Workers   int          // Number of workers, default runtime.NumCPU()
+	QueueSize int          // Maximum queued tasks, default 1024
+	Reject    RejectPolicy // Policy when the queue is full, default Abort
+}
+
+// Stats the metrics of a pool
+type Stats struct {
+	Name      string `json:"name"`
+	Workers   int    `json:"workers"`
+	Queued    int    `json:"queued"`
+	Active    int64  `json:"active"`
+	Completed int64  `json:"completed"`
+	Failed    int64  `json:"failed"`
+	Panicked  int64  `json:"panicked"`
+	Rejected  int64  `json:"rejected"`
+	Discarded int64  `json:"discarded"`
+}
+
+type item struct {
+	ctx  context.Context
+	task Task
+}
+
+// Pool a bounded worker pool
+type Pool struct {
+	conf       PoolConfig
+	queue      chan *item
+	mu         sync.RWMutex
+	closed     bool
+	wg         sync.WaitGroup
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+
+	active, completed, failed, panicked, rejected, discarded atomic.Int64
+}
+
+func newPool(conf PoolConfig) *Pool {
+	if conf.Workers <= 0 {
+		conf.Workers = runtime.NumCPU()
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 1024
+	}
+	p := &Pool{conf: conf, queue: make(chan *item, conf.QueueSize)}
+	p.baseCtx, p.cancelBase = context.WithCancel(context.Background())
+	for i := 0; i < conf.Workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Submit queue the task, the values of the ctx are propagated to the task but its cancellation is not.
+// A *gin.Context is copied so that it can be used after the request completes.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	if c, ok := ctx.(*gin.Context); ok {
+		ctx = c.Copy()
+	}
+	it := &item{ctx: context.WithoutCancel(ctx), task: task}
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrShutdown
+	}
+	select {
+	case p.queue <- it:
+		p.mu.RUnlock()
+		return nil
+	default:
+	}
+	switch p.conf.Reject {
+	case Discard:
+		p.mu.RUnlock()
+		p.discarded.Add(1)
+		return nil
+	case DiscardOldest:
+		defer p.mu.RUnlock()
+		select {
+		case <-p.queue:
+			p.discarded.Add(1)
+		default:
+		}
+		select {
+		case p.queue <- it:
+			return nil
+		default:
+			p.rejected.Add(1)
+			return ErrRejected
+		}
+	case CallerRuns:
+		p.mu.RUnlock()
+		p.run(it)
+		return nil
+	default:
+		p.mu.RUnlock()
+		p.rejected.Add(1)
+		return ErrRejected
+	}
+}
+
+// Stats the current metrics of the pool
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Name:      p.conf.Name,
+		Workers:   p.conf.Workers,
+		Queued:    len(p.queue),
+		Active:    p.active.Load(),
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+		Panicked:  p.panicked.Load(),
+		Rejected:  p.rejected.Load(),
+		Discarded: p.discarded.Load(),
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for it := range p.queue {
+		p.run(it)
+	}
+}
+
+// run the task, panics are recovered and reported through the exception package
+func (p *Pool) run(it *item) {
+	ctx, cancel := context.WithCancel(it.ctx)
+	stop := context.AfterFunc(p.baseCtx, cancel)
+	p.active.Add(1)
+	defer func() {
+		stop()
+		cancel()
+		p.active.Add(-1)
+		if r := recover(); r != nil {
+			p.panicked.Add(1)
+			logger.Log.Errorf("Executor pool %s task panic", p.conf.Name)
+			exception.Report(r)
+		}
+	}()
+	if err := it.task(ctx); err != nil {
+		p.failed.Add(1)
+		logger.Log.Errorf("Executor pool %s task failed, %s", p.conf.Name, err.Error())
+		return
+	}
+	p.completed.Add(1)
+}
+
+// shutdown stop accepting tasks and drain the queue, the running tasks are cancelled when the ctx is done
+func (p *Pool) shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		remaining := len(p.queue) + int(p.active.Load())
+		p.cancelBase()
+		return fmt.Errorf("executor pool %s drain timeout, %d tasks cancelled", p.conf.Name, remaining)
+	}
+}
+
+// Executor a set of named pools, it is a SmartLifecycle bean drained when the application shuts down
+type Executor struct {
+	pools map[string]*Pool
+	names []string
+}
+
+var (
+	registryOnce sync.Once
+	registryMu   sync.Mutex
+	registry     []*Executor
+)
+
+// New create an executor with the pools, a default pool is created if absent.
+// The pool stats are published to expvar as gin_plus_executor, which is shown by the management metrics endpoint.
+func New(configs ...PoolConfig) *Executor {
+	e := &Executor{pools: make(map[string]*Pool)}
+	for _, conf := range append(configs, PoolConfig{Name: DefaultPool}) {
+		if _, ok := e.pools[conf.Name]; ok {
+			continue
+		}
+		e.pools[conf.Name] = newPool(conf)
+		e.names = append(e.names, conf.Name)
+	}
+	registryOnce.Do(func() {
+		expvar.Publish("gin_plus_executor", expvar.Func(func() any {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+			var stats []Stats
+			for _, exec := range registry {
+				stats = append(stats, exec.Stats()...)
+			}
+			return stats
+		}))
+	})
+	registryMu.Lock()
+	registry = append(registry, e)
+	registryMu.Unlock()
+	return e
+}
+
+// Pool get the pool by name, nil if absent
+func (e *Executor) Pool(name string) *Pool {
+	return e.pools[name]
+}
+
+// Submit queue the task to the default pool
+func (e *Executor) Submit(ctx context.Context, task Task) error {
+	return e.pools[DefaultPool].Submit(ctx, task)
+}
+
+// Stats the metrics of all pools
+func (e *Executor) Stats() []Stats {
+	stats := make([]Stats, 0, len(e.names))
+	for _, name := range e.names {
+		stats = append(stats, e.pools[name].Stats())
+	}
+	return stats
+}
+
+// Start the workers are started on creation, nothing to do
+func (e *Executor) Start(context.Context) error {
+	return nil
+}
+
+// Stop drain all pools
+func (e *Executor) Stop(ctx context.Context) error {
+	var errs []error
+	for _, name := range e.names {
+		if err := e.pools[name].shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	registryMu.Lock()
+	for i, exec := range registry {
+		if exec == e {
+			registry = append(registry[:i], registry[i+1:]...)
+			break
+		}
+	}
+	registryMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// Phase the executor stops after the scheduler, so that scheduled jobs can still submit tasks while stopping
+func (e *Executor) Phase() int {
+	return 1 << 19
+}
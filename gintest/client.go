@@ -0,0 +1,162 @@
+package gintest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Client send requests to the application in memory
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+	header  http.Header
+}
+
+// WithHeader set a header sent with every request of the client
+func (c *Client) WithHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// Do send the request
+func (c *Client) Do(req *http.Request) *Response {
+	c.t.Helper()
+	for k, v := range c.header {
+		if req.Header.Get(k) == "" {
+			req.Header[k] = v
+		}
+	}
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, req)
+	return &Response{t: c.t, Recorder: recorder}
+}
+
+// Request send a request with the body
+func (c *Client) Request(method, path string, body io.Reader) *Response {
+	c.t.Helper()
+	return c.Do(httptest.NewRequest(method, path, body))
+}
+
+// Get send a GET request
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodGet, path, nil)
+}
+
+// Delete send a DELETE request
+func (c *Client) Delete(path string) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodDelete, path, nil)
+}
+
+// PostJSON send a POST request with the json body
+func (c *Client) PostJSON(path string, body any) *Response {
+	c.t.Helper()
+	return c.sendJSON(http.MethodPost, path, body)
+}
+
+// PutJSON send a PUT request with the json body
+func (c *Client) PutJSON(path string, body any) *Response {
+	c.t.Helper()
+	return c.sendJSON(http.MethodPut, path, body)
+}
+
+// PostForm send a POST request with the form body
+func (c *Client) PostForm(path string, form url.Values) *Response {
+	c.t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+func (c *Client) sendJSON(method, path string, body any) *Response {
+	c.t.Helper()
+	content, err := json.Marshal(body)
+	if err != nil {
+		c.t.Fatalf("marshal request body error, %s", err.Error())
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(content))
+	req.Header.Set("Content-Type", "application/json")
+	return c.Do(req)
+}
+
+// Envelope the decoded resp.Result, the data is kept raw so that it can be decoded into any type
+type Envelope struct {
+	Code    int             `json:"err_code"`
+	Message string          `json:"err_msg"`
+	Data    json.RawMessage `json:"ret"`
+}
+
+// Response the recorded response
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+	envelope *Envelope
+}
+
+// Envelope decode the body as resp.Result
+func (r *Response) Envelope() *Envelope {
+	r.t.Helper()
+	if r.envelope == nil {
+		r.envelope = &Envelope{}
+		if err := json.Unmarshal(r.Recorder.Body.Bytes(), r.envelope); err != nil {
+			r.t.Fatalf("decode response envelope error, %s, body: %s", err.Error(), r.Recorder.Body.String())
+		}
+	}
+	return r.envelope
+}
+
+// Decode decode the data of resp.Result into v
+func (r *Response) Decode(v any) *Response {
+	r.t.Helper()
+	data := r.Envelope().Data
+	if len(data) == 0 {
+		r.t.Fatalf("response has no data, body: %s", r.Recorder.Body.String())
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		r.t.Fatalf("decode response data error, %s", err.Error())
+	}
+	return r
+}
+
+// AssertStatus assert the http status code
+func (r *Response) AssertStatus(status int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != status {
+		r.t.Errorf("expected http status %d, got %d, body: %s", status, r.Recorder.Code, r.Recorder.Body.String())
+	}
+	return r
+}
+
+// AssertCode assert the business code of resp.Result
+func (r *Response) AssertCode(code int) *Response {
+	r.t.Helper()
+	if got := r.Envelope().Code; got != code {
+		r.t.Errorf("expected business code %d, got %d, message: %s", code, got, r.Envelope().Message)
+	}
+	return r
+}
+
+// AssertMessage assert the business message of resp.Result
+func (r *Response) AssertMessage(message string) *Response {
+	r.t.Helper()
+	if got := r.Envelope().Message; got != message {
+		r.t.Errorf("expected business message %q, got %q", message, got)
+	}
+	return r
+}
+
+// AssertHeader assert the response header
+func (r *Response) AssertHeader(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Header().Get(key); got != value {
+		r.t.Errorf("expected header %s to be %q, got %q", key, value, got)
+	}
+	return r
+}
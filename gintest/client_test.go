@@ -0,0 +1,94 @@
+package gintest_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+)
+
+type user struct {
+	Name string `json:"name" form:"name"`
+}
+
+type EchoController struct {
+	mvc.Controller
+}
+
+func (e *EchoController) Header(ctx *gin.Context) {
+	ctx.Header("X-Echo", ctx.GetHeader("X-Token"))
+	resp.Ok(ctx)
+}
+
+func (e *EchoController) Json(ctx *gin.Context) {
+	var u user
+	if resp.ParamInvalid(ctx, ctx.ShouldBindJSON(&u) != nil) {
+		return
+	}
+	resp.Json(ctx, u)
+}
+
+func (e *EchoController) Form(ctx *gin.Context) {
+	var u user
+	if resp.ParamInvalid(ctx, ctx.ShouldBind(&u) != nil) {
+		return
+	}
+	resp.Json(ctx, u)
+}
+
+func (e *EchoController) Delete(ctx *gin.Context) {
+	resp.BadRequest(ctx, true, "cannot delete")
+}
+
+func newEchoHarness(t *testing.T) *gintest.Harness {
+	core.Apis = map[string][]*core.MethodInfo{
+		"EchoController": {
+			{Method: http.MethodGet, ApiPath: "/header", Name: "Header", Annotations: map[string]string{}},
+			{Method: http.MethodPost, ApiPath: "/json", Name: "Json", Annotations: map[string]string{}},
+			{Method: http.MethodPut, ApiPath: "/json", Name: "Json", Annotations: map[string]string{}},
+			{Method: http.MethodPost, ApiPath: "/form", Name: "Form", Annotations: map[string]string{}},
+			{Method: http.MethodDelete, ApiPath: "/user", Name: "Delete", Annotations: map[string]string{}},
+		},
+	}
+	return gintest.New(t, gintest.WithController(&EchoController{}))
+}
+
+func TestClientHeader(t *testing.T) {
+	h := newEchoHarness(t)
+	h.Client().WithHeader("X-Token", "abc").Get("/header").AssertStatus(http.StatusOK).AssertCode(0).AssertHeader("X-Echo", "abc")
+}
+
+func TestClientJSON(t *testing.T) {
+	h := newEchoHarness(t)
+	var got user
+	h.Client().PostJSON("/json", user{Name: "tom"}).AssertCode(0).AssertMessage("OK").Decode(&got)
+	if got.Name != "tom" {
+		t.Errorf("expected tom, got %s", got.Name)
+	}
+	h.Client().PutJSON("/json", user{Name: "jerry"}).Decode(&got)
+	if got.Name != "jerry" {
+		t.Errorf("expected jerry, got %s", got.Name)
+	}
+}
+
+func TestClientForm(t *testing.T) {
+	h := newEchoHarness(t)
+	var got user
+	h.Client().PostForm("/form", url.Values{"name": {"spike"}}).AssertCode(0).Decode(&got)
+	if got.Name != "spike" {
+		t.Errorf("expected spike, got %s", got.Name)
+	}
+}
+
+func TestClientEnvelope(t *testing.T) {
+	h := newEchoHarness(t)
+	env := h.Client().Delete("/user").AssertCode(resp.BadRequestCode).AssertMessage("cannot delete").Envelope()
+	if len(env.Data) != 0 {
+		t.Errorf("expected no data, got %s", env.Data)
+	}
+}
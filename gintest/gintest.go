@@ -0,0 +1,235 @@
+package gintest
+
+import (
+	"context"
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/banner"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/ioc"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"testing"
+)
+
+// Test harness of controllers and full applications.
+// Example:
+//
+//	func TestHello(t *testing.T) {
+//	    h := gintest.New(t,
+//	        gintest.WithConfig("name: test"),
+//	        gintest.WithController(&controller.TestController{}),
+//	        gintest.WithMock(&mockMapper{}),
+//	    )
+//	    h.Client().Get("/hello").AssertStatus(http.StatusOK).AssertCode(0)
+//	}
+
+// the generated api metadata is consumed by mvc.Apply, keep a copy so that every harness can apply it again
+var apisSnapshot map[string][]*core.MethodInfo
+
+// Option customize the harness
+type Option func(h *Harness)
+
+// WithConfig the application configuration in yaml, the configuration file on disk is never read
+func WithConfig(yaml string) Option {
+	return func(h *Harness) {
+		h.configSources = append(h.configSources, &application.ContentSource{Content: []byte(yaml)})
+	}
+}
+
+// WithConfigSource add configuration sources after WithConfig
+func WithConfigSource(sources ...application.ConfigSource) Option {
+	return func(h *Harness) {
+		h.configSources = append(h.configSources, sources...)
+	}
+}
+
+// WithController register controllers to the application, they replace the registered controllers of the same type
+func WithController(controllers ...interface{ PostConstruct() }) Option {
+	return func(h *Harness) {
+		h.controllers = append(h.controllers, controllers...)
+	}
+}
+
+// WithMock override bean definitions with mocks, the mocks must be pointers.
+// A mock replaces the bean of the same type, and is injected into interface fields it implements.
+// The ioc container cannot remove beans, so a mock is only registered to it in place of an existing bean
+// of the same type, the other mocks are set on the fields of the injected beans. Both are reverted when the test finishes.
+func WithMock(mocks ...any) Option {
+	return func(h *Harness) {
+		h.mocks = append(h.mocks, mocks...)
+	}
+}
+
+// WithApp customize the application before it starts, such as adding interceptors
+func WithApp(f func(app *application.App)) Option {
+	return func(h *Harness) {
+		h.customizers = append(h.customizers, f)
+	}
+}
+
+// WithMiddleware replace the default middlewares of application.Default
+func WithMiddleware(middlewares ...gin.HandlerFunc) Option {
+	return func(h *Harness) {
+		h.middlewares = middlewares
+		h.customMiddlewares = true
+	}
+}
+
+// Harness a started application and its test client
+type Harness struct {
+	t                 testing.TB
+	app               *application.App
+	configSources     []application.ConfigSource
+	controllers       []interface{ PostConstruct() }
+	mocks             []any
+	customizers       []func(app *application.App)
+	middlewares       []gin.HandlerFunc
+	customMiddlewares bool
+}
+
+// New build and start an application, it is shut down when the test finishes.
+// The main server listens on a random port and the management server is disabled, requests of the client are served in memory.
+// The harness changes global state such as the mocked beans, the banner, application.Conf and the gin mode,
+// they are restored when the test finishes, so harnesses must not be used by parallel tests.
+func New(t testing.TB, options ...Option) *Harness {
+	t.Helper()
+	h := &Harness{t: t}
+	for _, option := range options {
+		option(h)
+	}
+	t.Cleanup(snapshot())
+	// random port so that tests never conflict with each other, management.port 0 disables the management server
+	sources := append(h.configSources, &application.ContentSource{Content: []byte("server:\n  port: 0\n  unix: \"\"\nmanagement:\n  port: 0\n")})
+	confOptions := []application.ConfigOption{application.WithoutConfigFile(), application.WithConfigSource(sources...)}
+	if h.customMiddlewares {
+		h.app = application.NewWithConfig(confOptions, h.middlewares...)
+	} else {
+		h.app = application.DefaultWithConfig(confOptions...)
+	}
+	gin.SetMode(gin.TestMode)
+	h.app.Banner("")
+	h.registerMocks()
+	restoreApis()
+	for _, c := range h.controllers {
+		mvc.Register(c)
+	}
+	for _, f := range h.customizers {
+		f(h.app)
+	}
+	if len(h.mocks) > 0 {
+		h.app.PreStart(func() {
+			var overridden []override
+			for _, c := range h.controllers {
+				overridden = overrideFields(reflect.ValueOf(c), h.mocks, make(map[uintptr]bool), overridden)
+			}
+			t.Cleanup(func() {
+				// the beans outlive the harness, give them back their own dependencies
+				for i := len(overridden) - 1; i >= 0; i-- {
+					overridden[i].field.Set(overridden[i].old)
+				}
+			})
+		})
+	}
+	if err := h.app.Start(context.Background()); err != nil {
+		t.Fatalf("start application error, %s", err.Error())
+	}
+	t.Cleanup(func() {
+		if err := h.app.Shutdown(context.Background()); err != nil {
+			t.Errorf("shutdown application error, %s", err.Error())
+		}
+	})
+	return h
+}
+
+// App the application under test
+func (h *Harness) App() *application.App {
+	return h.app
+}
+
+// Client a new client sending requests to the application in memory
+func (h *Harness) Client() *Client {
+	return &Client{t: h.t, handler: h.app.Engine(), header: make(map[string][]string)}
+}
+
+// registerMocks register the mocks replacing an existing bean of the same type, the replaced beans are restored when the test finishes
+func (h *Harness) registerMocks() {
+	for _, mock := range h.mocks {
+		previous := ioc.GetBeanByName(reflect.TypeOf(mock).Elem().String())
+		if previous == nil {
+			continue
+		}
+		ioc.SetBeans(mock)
+		h.t.Cleanup(func() {
+			ioc.SetBeans(previous)
+		})
+	}
+}
+
+// snapshot the global state changed by the harness, returns the func restoring it
+func snapshot() func() {
+	bannerText, conf, mode := banner.Banner, *application.Conf, gin.Mode()
+	return func() {
+		banner.Banner = bannerText
+		*application.Conf = conf
+		gin.SetMode(mode)
+	}
+}
+
+// restoreApis keep a copy of the api metadata set since the last harness, or restore the copy consumed by the last harness
+func restoreApis() {
+	if core.Apis != nil {
+		apisSnapshot = core.Apis
+	}
+	if apisSnapshot == nil {
+		return
+	}
+	core.Apis = make(map[string][]*core.MethodInfo, len(apisSnapshot))
+	for k, v := range apisSnapshot {
+		core.Apis[k] = v
+	}
+}
+
+// override a field replaced by a mock and its previous value
+type override struct {
+	field reflect.Value
+	old   reflect.Value
+}
+
+// overrideFields walk the injected bean graph and replace the fields of the mock types or of the interfaces they implement,
+// returns the replaced fields appended to overridden
+func overrideFields(v reflect.Value, mocks []any, visited map[uintptr]bool, overridden []override) []override {
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return overridden
+	}
+	if visited[v.Pointer()] {
+		return overridden
+	}
+	visited[v.Pointer()] = true
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		for _, mock := range mocks {
+			mt := reflect.TypeOf(mock)
+			if mt == f.Type || (f.Type.Kind() == reflect.Interface && mt.Implements(f.Type)) {
+				if fv.Interface() != mock {
+					old := reflect.New(f.Type).Elem()
+					old.Set(fv)
+					overridden = append(overridden, override{field: fv, old: old})
+					fv.Set(reflect.ValueOf(mock))
+				}
+				break
+			}
+		}
+		if fv.Kind() == reflect.Interface {
+			fv = fv.Elem()
+		}
+		overridden = overrideFields(fv, mocks, visited, overridden)
+	}
+	return overridden
+}
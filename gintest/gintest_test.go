@@ -0,0 +1,124 @@
+package gintest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/ioc"
+	"github.com/gin-gonic/gin"
+)
+
+type Greeter interface {
+	Greet() string
+}
+
+type realGreeter struct{}
+
+func (r *realGreeter) Greet() string { return "real" }
+
+type mockGreeter struct{}
+
+func (m *mockGreeter) Greet() string { return "mock" }
+
+// Welcome a shared bean depending on the greeter
+type Welcome struct {
+	Greeter Greeter
+}
+
+type GreetController struct {
+	mvc.Controller
+	Greeter Greeter
+	Welcome *Welcome
+}
+
+func (g *GreetController) Hello(ctx *gin.Context) {
+	resp.Json(ctx, g.Greeter.Greet())
+}
+
+func (g *GreetController) SayWelcome(ctx *gin.Context) {
+	resp.Json(ctx, g.Welcome.Greeter.Greet())
+}
+
+var welcome = &Welcome{Greeter: &realGreeter{}}
+
+func init() {
+	ioc.SetBeans(&realGreeter{}, welcome)
+}
+
+func setApis() {
+	core.Apis = map[string][]*core.MethodInfo{
+		"GreetController": {
+			{Method: http.MethodGet, ApiPath: "/hello", Name: "Hello", Annotations: map[string]string{}},
+			{Method: http.MethodGet, ApiPath: "/welcome", Name: "SayWelcome", Annotations: map[string]string{}},
+		},
+	}
+}
+
+func hello(t *testing.T, options ...gintest.Option) string {
+	var greeting string
+	setApis()
+	t.Run("hello", func(t *testing.T) {
+		h := gintest.New(t, append(options, gintest.WithController(&GreetController{}))...)
+		h.Client().Get("/hello").AssertStatus(http.StatusOK).AssertCode(0).Decode(&greeting)
+	})
+	return greeting
+}
+
+func TestWithMock(t *testing.T) {
+	if got := hello(t, gintest.WithMock(&mockGreeter{})); got != "mock" {
+		t.Errorf("expected the mock, got %s", got)
+	}
+}
+
+func TestMockIsolation(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		hello(t, gintest.WithMock(&mockGreeter{}))
+		if got := hello(t); got != "real" {
+			t.Fatalf("run %d: expected the real bean after the mock harness finished, got %s", i, got)
+		}
+	}
+}
+
+func welcomeOf(t *testing.T, options ...gintest.Option) string {
+	var greeting string
+	setApis()
+	t.Run("welcome", func(t *testing.T) {
+		h := gintest.New(t, append(options, gintest.WithController(&GreetController{}))...)
+		h.Client().Get("/welcome").AssertStatus(http.StatusOK).Decode(&greeting)
+	})
+	return greeting
+}
+
+func TestMockedBeanRestored(t *testing.T) {
+	if got := welcomeOf(t, gintest.WithMock(&Welcome{Greeter: &mockGreeter{}})); got != "mock" {
+		t.Errorf("expected the mocked bean, got %s", got)
+	}
+	if got := ioc.GetBean(&Welcome{}); got != welcome {
+		t.Errorf("expected the replaced bean to be registered again, got %v", got)
+	}
+	if got := welcomeOf(t, gintest.WithMock(&mockGreeter{})); got != "mock" {
+		t.Errorf("expected the mock injected into the shared bean, got %s", got)
+	}
+	if got := welcome.Greeter.Greet(); got != "real" {
+		t.Errorf("expected the shared bean to keep its own greeter, got %s", got)
+	}
+}
+
+func TestConfigRestored(t *testing.T) {
+	size := application.Conf.Server.MaxFileSize
+	t.Run("config", func(t *testing.T) {
+		core.Apis = map[string][]*core.MethodInfo{}
+		gintest.New(t, gintest.WithConfig("server:\n  max_file_size: 1024\n"), gintest.WithController(&GreetController{}))
+		if got := application.Conf.Server.MaxFileSize; got != 1024 {
+			t.Errorf("expected the max file size of the config, got %d", got)
+		}
+	})
+	if got := application.Conf.Server.MaxFileSize; got != size {
+		t.Errorf("expected the max file size to be restored to %d, got %d", size, got)
+	}
+}
@@ -0,0 +1,212 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/internal/intercept"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/gin-plus/v3/security"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// Idempotent apis with the Idempotency-Key header, retries with the same key replay the first response.
+// Example:
+//
+//	app.Interceptor(security.New(tokens), idempotency.New(idempotency.Config{}))
+//
+//	// Create
+//	// @POST(path="/order")
+//	// @Idempotent(ttl="24h", required=true)
+//	func (o *OrderController) Create(ctx *gin.Context)
+
+// AnnotationName the annotation declaring the api idempotent
+const AnnotationName = "Idempotent"
+
+// ReplayedHeader the header marking a replayed response
+const ReplayedHeader = "Idempotent-Replayed"
+
+// recorderKey the key of the response recorder in the gin context
+const recorderKey = "gin-plus/idempotency/recorder"
+
+// Config the idempotency configuration, read it from app.yml with application.UnmarshalKey so that bare durations are seconds
+type Config struct {
+	Header      string        `mapstructure:"header"`       // Header carrying the key, default Idempotency-Key
+	TTL         time.Duration `mapstructure:"ttl"`          // How long the response is kept, default 24h
+	LockTimeout time.Duration `mapstructure:"lock_timeout"` // How long a key stays locked when the response is never saved, default 1m
+}
+
+// Response the stored response
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Store keep the locks and the responses. With the in-memory store a retry routed to another instance
+// runs the api again, so the instances behind the same load balancer must share the store
+type Store interface {
+	// Lock reserve the key, returns false when it is locked or already has a response
+	Lock(ctx context.Context, key string, timeout time.Duration) (acquired bool, err error)
+	// Get returns nil when the key has no response
+	Get(ctx context.Context, key string) (*Response, error)
+	// Save the response and release the lock
+	Save(ctx context.Context, key string, res *Response, ttl time.Duration) error
+	// Unlock release the lock without saving, so that the request can be retried
+	Unlock(ctx context.Context, key string) error
+}
+
+// annotation the parameters of @Idempotent
+type annotation struct {
+	TTL      time.Duration `anno:"ttl"`      // Overrides Config.TTL
+	Required bool          `anno:"required"` // Reject the requests without the key, default false means they are served as usual
+}
+
+// Interceptor the idempotency interceptor of the apis annotated with @Idempotent.
+// Add it after the security interceptor, the key is scoped by the principal, the method and the path.
+type Interceptor struct {
+	conf  Config
+	store Store
+}
+
+// New Create the idempotency interceptor with the in-memory store
+func New(conf Config) *Interceptor {
+	if conf.Header == "" {
+		conf.Header = "Idempotency-Key"
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = 24 * time.Hour
+	}
+	if conf.LockTimeout <= 0 {
+		conf.LockTimeout = time.Minute
+	}
+	return &Interceptor{conf: conf, store: NewMemoryStore()}
+}
+
+// Store Sets the store of the responses
+func (i *Interceptor) Store(store Store) *Interceptor {
+	i.store = store
+	return i
+}
+
+func (i *Interceptor) Predicate(ctx *gin.Context) bool {
+	_, ok := mvc.GetAnnotation(ctx, AnnotationName)
+	return ok
+}
+
+func (i *Interceptor) PreHandle(ctx *gin.Context) {
+	var anno annotation
+	if annos := mvc.GetAnnotations(ctx, AnnotationName); len(annos) > 0 {
+		if err := annos[0].Decode(&anno); err != nil {
+			logger.Log.Errorf("invalid @%s of %s, %s", AnnotationName, ctx.FullPath(), err.Error())
+		}
+	}
+	idempotencyKey := ctx.GetHeader(i.conf.Header)
+	if idempotencyKey == "" {
+		if anno.Required {
+			resp.BadRequest(ctx, true, "缺少请求头 "+i.conf.Header)
+			ctx.Abort()
+		}
+		return
+	}
+	key := i.key(ctx, idempotencyKey)
+	if i.replay(ctx, key) {
+		return
+	}
+	acquired, err := i.store.Lock(ctx, key, i.conf.LockTimeout)
+	if err != nil {
+		logger.Log.Errorf("idempotency store error, %s", err.Error())
+		resp.SeverError(ctx, true)
+		ctx.Abort()
+		return
+	}
+	if !acquired {
+		// the first request may have completed after the previous lookup
+		if i.replay(ctx, key) {
+			return
+		}
+		resp.InitResp(ctx, http.StatusConflict).WithCode(resp.ConflictCode).WithMessage("请求正在处理中，请勿重复提交").To()
+		ctx.Abort()
+		return
+	}
+	ttl := anno.TTL
+	if ttl <= 0 {
+		ttl = i.conf.TTL
+	}
+	rec := &recorder{Recorder: intercept.NewRecorder(ctx.Writer), key: key, ttl: ttl}
+	ctx.Writer = rec.Recorder
+	ctx.Set(recorderKey, rec)
+}
+
+func (i *Interceptor) PostHandle(ctx *gin.Context) {
+	if v, ok := ctx.Get(recorderKey); ok {
+		v.(*recorder).completed = true
+	}
+}
+
+// AfterCompletion save the response when the method completed successfully, otherwise release the key so that the request can be retried
+func (i *Interceptor) AfterCompletion(ctx *gin.Context) {
+	v, ok := ctx.Get(recorderKey)
+	if !ok {
+		return
+	}
+	rec := v.(*recorder)
+	// store a detached context, the request context may be cancelled when the client goes away
+	storeCtx := context.WithoutCancel(ctx.Request.Context())
+	if !rec.completed || rec.Status() >= http.StatusInternalServerError || ctx.GetInt("bcode") >= resp.SystemErrorCode {
+		// panics, aborted requests and server errors, including the ones replied with http 200 and
+		// a system business code, are not stored
+		if err := i.store.Unlock(storeCtx, rec.key); err != nil {
+			logger.Log.Errorf("idempotency store error, %s", err.Error())
+		}
+		return
+	}
+	header := rec.Header().Clone()
+	header.Del("Date")
+	res := &Response{Status: rec.Status(), Header: header, Body: rec.Body()}
+	if err := i.store.Save(storeCtx, rec.key, res, rec.ttl); err != nil {
+		logger.Log.Errorf("idempotency store error, %s", err.Error())
+	}
+}
+
+// key scope the idempotency key by the principal, the method and the path
+func (i *Interceptor) key(ctx *gin.Context, idempotencyKey string) string {
+	owner := ctx.ClientIP()
+	if p, ok := security.Current(ctx); ok {
+		owner = "principal:" + p.ID
+	}
+	return owner + "|" + ctx.Request.Method + " " + ctx.FullPath() + "|" + idempotencyKey
+}
+
+// replay write the stored response, returns false when the key has no response
+func (i *Interceptor) replay(ctx *gin.Context, key string) bool {
+	res, err := i.store.Get(ctx, key)
+	if err != nil {
+		logger.Log.Errorf("idempotency store error, %s", err.Error())
+		resp.SeverError(ctx, true)
+		ctx.Abort()
+		return true
+	}
+	if res == nil {
+		return false
+	}
+	header := ctx.Writer.Header()
+	for k, v := range res.Header {
+		header[k] = v
+	}
+	header.Set(ReplayedHeader, "true")
+	ctx.Status(res.Status)
+	_, _ = ctx.Writer.Write(res.Body)
+	ctx.Abort()
+	return true
+}
+
+// recorder the response of the first request and where to store it
+type recorder struct {
+	*intercept.Recorder
+	completed bool // the method completed without panic
+	key       string
+	ttl       time.Duration
+}
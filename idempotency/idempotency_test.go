@@ -0,0 +1,83 @@
+package idempotency_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/idempotency"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+)
+
+type OrderController struct {
+	mvc.Controller
+	calls int
+	fail  bool
+	panic bool
+}
+
+func (o *OrderController) Create(ctx *gin.Context) {
+	o.calls++
+	if o.panic {
+		panic(errors.New("database is down"))
+	}
+	if o.fail {
+		resp.SeverError(ctx, true)
+		return
+	}
+	resp.Json(ctx, o.calls)
+}
+
+func newHarness(t *testing.T, c *OrderController) *gintest.Harness {
+	core.Apis = map[string][]*core.MethodInfo{
+		"OrderController": {{Method: http.MethodPost, ApiPath: "/order", Name: "Create", Annotations: map[string]string{idempotency.AnnotationName: ""}}},
+	}
+	return gintest.New(t, gintest.WithController(c), gintest.WithApp(func(app *application.App) {
+		app.Interceptor(idempotency.New(idempotency.Config{}))
+	}))
+}
+
+func post(h *gintest.Harness, key string) *gintest.Response {
+	req := httptest.NewRequest(http.MethodPost, "/order", nil)
+	req.Header.Set("Idempotency-Key", key)
+	return h.Client().Do(req)
+}
+
+func TestReplay(t *testing.T) {
+	c := &OrderController{}
+	h := newHarness(t, c)
+	post(h, "k1").AssertStatus(http.StatusOK).AssertCode(0)
+	post(h, "k1").AssertHeader(idempotency.ReplayedHeader, "true").AssertCode(0)
+	if c.calls != 1 {
+		t.Errorf("expected the handler to run once, got %d", c.calls)
+	}
+}
+
+func TestServerErrorNotStored(t *testing.T) {
+	c := &OrderController{fail: true}
+	h := newHarness(t, c)
+	post(h, "k2").AssertStatus(http.StatusOK).AssertCode(resp.SystemErrorCode)
+	c.fail = false
+	post(h, "k2").AssertHeader(idempotency.ReplayedHeader, "").AssertCode(0)
+	if c.calls != 2 {
+		t.Errorf("expected the failed request to be retried, got %d calls", c.calls)
+	}
+}
+
+func TestPanicReleased(t *testing.T) {
+	c := &OrderController{panic: true}
+	h := newHarness(t, c)
+	post(h, "k3").AssertCode(resp.SystemErrorCode)
+	c.panic = false
+	post(h, "k3").AssertHeader(idempotency.ReplayedHeader, "").AssertStatus(http.StatusOK).AssertCode(0)
+	post(h, "k3").AssertHeader(idempotency.ReplayedHeader, "true").AssertCode(0)
+	if c.calls != 2 {
+		t.Errorf("expected the panicked request to be retried once, got %d calls", c.calls)
+	}
+}
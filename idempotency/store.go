@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore the in-memory store, the keys are per instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	res      *Response // nil while the first request is in progress
+	expireAt time.Time
+}
+
+// NewMemoryStore Create an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry), lastSweep: time.Now()}
+}
+
+func (m *MemoryStore) Lock(ctx context.Context, key string, timeout time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.lastSweep) >= time.Minute {
+		m.lastSweep = now
+		for k, e := range m.entries {
+			if now.After(e.expireAt) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	if e, ok := m.entries[key]; ok && !now.After(e.expireAt) {
+		return false, nil
+	}
+	m.entries[key] = &entry{expireAt: now.Add(timeout)}
+	return true, nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (*Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || time.Now().After(e.expireAt) {
+		return nil, nil
+	}
+	return e.res, nil
+}
+
+func (m *MemoryStore) Save(ctx context.Context, key string, res *Response, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = &entry{res: res, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Unlock(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok && e.res == nil {
+		delete(m.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package filewatch
+
+import (
+	"fmt"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/fsnotify/fsnotify"
+	"path/filepath"
+)
+
+// Watcher reload files when their directories change
+type Watcher struct {
+	watcher *fsnotify.Watcher
+}
+
+// Watch the directories of the files and call reload when anything in them changes.
+// The directories are watched rather than the files, so that atomic replacement is detected, such as kubernetes
+// replacing the ..data symlink of a mounted secret. A failed reload is logged and the previous state is kept
+// until the next change, because the files may be half written. The name describes the files in the logs.
+func Watch(name string, files []string, reload func() error) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		dirs[filepath.Dir(file)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err = watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("watch %s error, %w", name, err)
+		}
+	}
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+					if err := reload(); err != nil {
+						logger.Log.Warnf("reload %s failed, %s", name, err.Error())
+						continue
+					}
+					logger.Log.Debugf("%s reloaded", name)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Log.Warnf("watch %s error, %s", name, err.Error())
+			}
+		}
+	}()
+	return &Watcher{watcher: watcher}, nil
+}
+
+// Close stop watching
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
@@ -0,0 +1,47 @@
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAtomicReplace(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "keys.json")
+	if err := os.WriteFile(file, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := make(chan string, 10)
+	w, err := Watch("keys", []string{file}, func() error {
+		content, err := os.ReadFile(file)
+		if err == nil {
+			reloaded <- string(content)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// replace the file by renaming another one over it, as the config management tools do
+	tmp := filepath.Join(dir, ".keys.json.tmp")
+	if err = os.WriteFile(tmp, []byte("v2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case content := <-reloaded:
+			if content == "v2" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the replaced file to be reloaded")
+		}
+	}
+}
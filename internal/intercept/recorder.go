@@ -0,0 +1,89 @@
+package intercept
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+)
+
+// Recorder capture the body of the response.
+// By default the body is also written to the client as it goes, a buffered recorder holds
+// the whole response until Commit, so that headers computed from the body can be set.
+type Recorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+	written  bool // WriteHeaderNow is called on the buffered recorder
+}
+
+// NewRecorder Create a recorder writing to the client as it goes
+func NewRecorder(w gin.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+// NewBufferedRecorder Create a recorder holding the response until Commit
+func NewBufferedRecorder(w gin.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w, buffered: true}
+}
+
+// Body the captured body
+func (r *Recorder) Body() []byte {
+	return r.body.Bytes()
+}
+
+// Commit write the buffered response to the client
+func (r *Recorder) Commit() {
+	if !r.buffered || !r.Written() {
+		return
+	}
+	r.buffered = false
+	r.ResponseWriter.WriteHeaderNow()
+	_, _ = r.ResponseWriter.Write(r.body.Bytes())
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	if r.buffered {
+		return len(b), nil
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *Recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	if r.buffered {
+		return len(s), nil
+	}
+	return r.ResponseWriter.WriteString(s)
+}
+
+func (r *Recorder) WriteHeaderNow() {
+	if r.buffered {
+		r.written = true
+		return
+	}
+	r.ResponseWriter.WriteHeaderNow()
+}
+
+func (r *Recorder) Written() bool {
+	if r.buffered {
+		return r.written || r.body.Len() > 0
+	}
+	return r.ResponseWriter.Written()
+}
+
+func (r *Recorder) Size() int {
+	if r.buffered {
+		if !r.Written() {
+			return -1
+		}
+		return r.body.Len()
+	}
+	return r.ResponseWriter.Size()
+}
+
+// Flush the buffered response is sent as a whole on Commit
+func (r *Recorder) Flush() {
+	if !r.buffered {
+		r.ResponseWriter.Flush()
+	}
+}
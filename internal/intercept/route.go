@@ -0,0 +1,42 @@
+package intercept
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"sync"
+)
+
+// Helpers shared by the interceptors of the framework, they are not part of the public api.
+
+// MatchPath match the path, a trailing * of the pattern matches the prefix
+func MatchPath(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return pattern == path
+}
+
+// MatchRoute match the method and the path of a route rule, an empty method matches all methods
+func MatchRoute(ruleMethod, rulePath, method, path string) bool {
+	if ruleMethod != "" && !strings.EqualFold(ruleMethod, method) {
+		return false
+	}
+	return MatchPath(rulePath, path)
+}
+
+// RouteCache the values resolved once per route, keyed by the method and the route path.
+// The annotations and the configuration of a route never change after start, so neither does the value.
+type RouteCache[T any] struct {
+	values sync.Map
+}
+
+// Load returns the value of the route of the request, resolve is called on the first request of the route
+func (c *RouteCache[T]) Load(ctx *gin.Context, resolve func() T) T {
+	key := ctx.Request.Method + " " + ctx.FullPath()
+	if v, ok := c.values.Load(key); ok {
+		return v.(T)
+	}
+	v := resolve()
+	c.values.Store(key, v)
+	return v
+}
@@ -0,0 +1,241 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mitchellh/mapstructure"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Annotation a parsed annotation, such as @RateLimit(qps=10, burst=20) or @RequiresRoles("admin", "ops")
+type Annotation struct {
+	Name   string            // Annotation name without @
+	Value  string            // Raw value
+	Args   []string          // Positional arguments
+	Params map[string]string // Named parameters, list values such as [a, b] are joined by commas
+}
+
+// Decode the annotation into the struct pointed by v.
+// Fields are matched by the "anno" tag or the field name case-insensitively, positional arguments are mapped to
+// the "value" field, a single one as it is and several ones as a list.
+// Values are converted weakly, such as "10" to int, "2s" to time.Duration and "a,b" to []string.
+// Example:
+//
+//	type RateLimit struct {
+//	    QPS   int `anno:"qps"`
+//	    Burst int `anno:"burst"`
+//	}
+func (a Annotation) Decode(v any) error {
+	input := make(map[string]any, len(a.Params)+1)
+	for k, val := range a.Params {
+		input[k] = val
+	}
+	switch len(a.Args) {
+	case 0:
+	case 1:
+		input["value"] = a.Args[0]
+	default:
+		input["value"] = a.Args
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "anno",
+		WeaklyTypedInput: true,
+		Result:           v,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	if err = decoder.Decode(input); err != nil {
+		return fmt.Errorf("decode annotation @%s error, %w", a.Name, err)
+	}
+	return nil
+}
+
+// ParseAnnotation parse the raw value of an annotation, the surrounding parentheses are optional.
+// Parameters are separated by commas outside quotes and brackets, a parameter with "=" is named, otherwise positional.
+func ParseAnnotation(name, value string) (Annotation, error) {
+	anno := Annotation{Name: name, Value: value, Params: make(map[string]string)}
+	raw := strings.TrimSpace(value)
+	if strings.HasPrefix(raw, "(") && strings.HasSuffix(raw, ")") {
+		raw = strings.TrimSpace(raw[1 : len(raw)-1])
+	}
+	if raw == "" {
+		return anno, nil
+	}
+	parts, err := splitTopLevel(raw)
+	if err != nil {
+		return anno, fmt.Errorf("parse annotation @%s error, %w", name, err)
+	}
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if k, v, ok := cutTopLevel(part, '='); ok {
+			anno.Params[strings.TrimSpace(k)] = parseValue(v)
+			continue
+		}
+		anno.Args = append(anno.Args, parseValue(part))
+	}
+	return anno, nil
+}
+
+// parseAnnotations parse the annotations of a method, a value with several lines is treated as repeated annotations.
+// The map keeps one value per name, so the generator of core.Apis must join the values of a repeated annotation
+// with "\n", a generator keeping only the last value loses the others
+func parseAnnotations(annotations map[string]string) ([]Annotation, error) {
+	names := make([]string, 0, len(annotations))
+	for name := range annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var result []Annotation
+	for _, name := range names {
+		for _, line := range strings.Split(annotations[name], "\n") {
+			anno, err := ParseAnnotation(name, line)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, anno)
+		}
+	}
+	return result, nil
+}
+
+// controllerAnnotations parse the controller level annotations declared by the tag of the embedded Controller.
+// Example:
+//
+//	type UserController struct {
+//	    mvc.Controller `RequiresRoles:"admin" RateLimit:"qps=10, burst=20"`
+//	}
+func controllerAnnotations(controllerType reflect.Type) ([]Annotation, error) {
+	f, ok := controllerType.FieldByName("Controller")
+	if !ok || !f.Anonymous || f.Type != reflect.TypeOf(Controller{}) {
+		return nil, nil
+	}
+	var result []Annotation
+	tag := string(f.Tag)
+	for tag != "" {
+		// the same syntax as reflect.StructTag: name:"value" pairs separated by spaces
+		tag = strings.TrimLeft(tag, " ")
+		i := strings.Index(tag, ":\"")
+		if i <= 0 {
+			break
+		}
+		name := tag[:i]
+		tag = tag[i+1:]
+		quoted, err := strconv.QuotedPrefix(tag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation tag of %s, %w", controllerType.Name(), err)
+		}
+		tag = tag[len(quoted):]
+		value, _ := strconv.Unquote(quoted)
+		anno, err := ParseAnnotation(name, value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, anno)
+	}
+	return result, nil
+}
+
+// inheritAnnotations the controller annotations are inherited unless the method declares the same name
+func inheritAnnotations(controller, method []Annotation) []Annotation {
+	declared := make(map[string]bool, len(method))
+	for _, anno := range method {
+		declared[anno.Name] = true
+	}
+	result := make([]Annotation, 0, len(controller)+len(method))
+	for _, anno := range controller {
+		if !declared[anno.Name] {
+			result = append(result, anno)
+		}
+	}
+	return append(result, method...)
+}
+
+// GetAnnotations Gets all annotations of the name for the current api, including the inherited ones
+func GetAnnotations(ctx *gin.Context, annotationName string) []Annotation {
+	var result []Annotation
+	for _, anno := range lookupAnnotations(ctx) {
+		if anno.Name == annotationName {
+			result = append(result, anno)
+		}
+	}
+	return result
+}
+
+// GetAnnotationAs Gets the first annotation of the name and decodes it into T.
+// The has is false when the current api has no such annotation.
+// Example:
+//
+//	limit, has, err := mvc.GetAnnotationAs[RateLimit](ctx, "RateLimit")
+func GetAnnotationAs[T any](ctx *gin.Context, annotationName string) (val T, has bool, err error) {
+	annos := GetAnnotations(ctx, annotationName)
+	if len(annos) == 0 {
+		return val, false, nil
+	}
+	err = annos[0].Decode(&val)
+	return val, true, err
+}
+
+func splitTopLevel(s string) ([]string, error) {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quoted || depth != 0 {
+		return nil, fmt.Errorf("unbalanced quotes or brackets in %q", s)
+	}
+	return append(parts, s[start:]), nil
+}
+
+func cutTopLevel(s string, sep byte) (before, after string, found bool) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// parseValue unquote the value, a list such as ["a", "b"] is joined by commas
+func parseValue(v string) string {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		items, err := splitTopLevel(v[1 : len(v)-1])
+		if err != nil {
+			return v
+		}
+		for i, item := range items {
+			items[i] = parseValue(item)
+		}
+		return strings.Join(items, ",")
+	}
+	if unquoted, err := strconv.Unquote(v); err == nil {
+		return unquoted
+	}
+	return v
+}
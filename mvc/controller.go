@@ -1,10 +1,14 @@
 package mvc
 
 import (
+	"context"
 	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/event"
+	"github.com/archine/gin-plus/v3/plugin/logger"
 	"github.com/archine/ioc"
 	"github.com/gin-gonic/gin"
 	"reflect"
+	"strings"
 )
 
 // Annotations the annotation of Api method
@@ -13,8 +17,20 @@ type Annotations map[string]string
 // Global controller cache
 var controllerCache []abstractController
 
-// Annotations of each API
-var annotationCache map[string]Annotations
+// Controllers that have been applied to the gin engine
+var appliedControllers []any
+
+// Annotations of each API, keyed by HTTP method and path
+var annotationCache map[routeKey][]Annotation
+
+// Annotations of each API, keyed by handler name such as UserController.Get and the qualified one such as
+// github.com/foo/api.UserController.Get
+var handlerAnnotationCache map[string][]Annotation
+
+type routeKey struct {
+	method string
+	path   string
+}
 
 type abstractController interface {
 	// PostConstruct Triggered after dependency injection is completed. You can continue to decorate the controller here
@@ -28,8 +44,21 @@ type Controller struct{}
 func (c *Controller) PostConstruct() {}
 
 // Register controllers
+// registering a controller of the same type again replaces the previous one
 func Register(controller ...abstractController) {
-	controllerCache = append(controllerCache, controller...)
+	for _, c := range controller {
+		replaced := false
+		for i, cached := range controllerCache {
+			if reflect.TypeOf(cached) == reflect.TypeOf(c) {
+				controllerCache[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			controllerCache = append(controllerCache, c)
+		}
+	}
 }
 
 // IsController Determine whether it is controller
@@ -45,24 +74,32 @@ func IsController(v interface{}) bool {
 // @param e: gin.Engine
 // @param autowired: whether enable autowired properties
 func Apply(e *gin.Engine, autowired bool) {
+	appliedControllers = nil
 	if core.Apis == nil {
 		for _, controller := range controllerCache {
 			if autowired {
 				ioc.Inject(controller)
 			}
+			appliedControllers = append(appliedControllers, controller)
 		}
 		return
 	}
 	ginProxy := reflect.ValueOf(e)
-	annotationCache = make(map[string]Annotations)
+	annotationCache = make(map[routeKey][]Annotation)
+	handlerAnnotationCache = make(map[string][]Annotation)
 	for _, controller := range controllerCache {
 		if autowired {
 			ioc.Inject(controller)
 		}
 		controller.PostConstruct()
+		appliedControllers = append(appliedControllers, controller)
 		controllerTypeOf := reflect.TypeOf(controller).Elem()
 		controllerProxy := reflect.ValueOf(controller)
 		methodInfosAst := core.Apis[controllerTypeOf.Name()]
+		inherited, err := controllerAnnotations(controllerTypeOf)
+		if err != nil {
+			logger.Log.Fatal(err.Error())
+		}
 		for _, m := range methodInfosAst {
 			mValueProxy := controllerProxy.MethodByName(m.Name)
 			if mValueProxy.Kind() == reflect.Invalid {
@@ -72,7 +109,18 @@ func Apply(e *gin.Engine, autowired bool) {
 			args := []reflect.Value{reflect.ValueOf(m.ApiPath)}
 			args = append(args, mValueProxy)
 			ginMethod.Call(args)
-			annotationCache[m.ApiPath] = m.Annotations
+			annos, err := parseAnnotations(m.Annotations)
+			if err != nil {
+				logger.Log.Fatalf("%s.%s, %s", controllerTypeOf.Name(), m.Name, err.Error())
+			}
+			annos = inheritAnnotations(inherited, annos)
+			annotationCache[routeKey{method: strings.ToUpper(m.Method), path: m.ApiPath}] = annos
+			handlerAnnotationCache[controllerTypeOf.Name()+"."+m.Name] = annos
+			handlerAnnotationCache[controllerTypeOf.PkgPath()+"."+controllerTypeOf.Name()+"."+m.Name] = annos
+			routeEvent := event.RouteRegistered{Method: m.Method, Path: m.ApiPath, Controller: controllerTypeOf.Name(), Handler: m.Name}
+			if err := event.Publish(context.Background(), routeEvent); err != nil {
+				logger.Log.Errorf("publish route registered event error, %s", err.Error())
+			}
 		}
 		if len(controllerCache) == 1 {
 			controllerCache = nil
@@ -83,15 +131,41 @@ func Apply(e *gin.Engine, autowired bool) {
 	core.Apis = nil // GC
 }
 
+// Controllers returns the controllers that have been applied to the gin engine
+func Controllers() []any {
+	return appliedControllers
+}
+
 // GetAnnotation Gets the specified annotation
-// Returns the value of this annotation, when the has is false mine this val is empty
+// Returns the raw value of this annotation, when the has is false mine this val is empty.
+// Use GetAnnotations or GetAnnotationAs for the parsed parameters.
 func GetAnnotation(ctx *gin.Context, annotationName string) (val string, has bool) {
-	anno, has := annotationCache[ctx.FullPath()]
-	if !has || len(anno) == 0 {
+	annos := GetAnnotations(ctx, annotationName)
+	if len(annos) == 0 {
 		return "", false
 	}
-	val, has = anno[annotationName]
-	return
+	return annos[0].Value, true
+}
+
+// RouteAnnotations Gets all annotations of the api registered with the HTTP method and path, such as GET /user/:id
+func RouteAnnotations(method, path string) []Annotation {
+	if annos, ok := annotationCache[routeKey{method: strings.ToUpper(method), path: path}]; ok {
+		return annos
+	}
+	// apis registered with Any match every method
+	return annotationCache[routeKey{method: "ANY", path: path}]
+}
+
+// HandlerAnnotations Gets all annotations of the api method, the handler is the controller and method name such as UserController.Get.
+// When controllers of different packages share the name the last applied one wins, qualify the handler with the package path
+// of the controller such as github.com/foo/api.UserController.Get to tell them apart
+func HandlerAnnotations(handler string) []Annotation {
+	return handlerAnnotationCache[handler]
+}
+
+// lookupAnnotations the annotations of the current api
+func lookupAnnotations(ctx *gin.Context) []Annotation {
+	return RouteAnnotations(ctx.Request.Method, ctx.FullPath())
 }
 
 // MethodInterceptor API method interceptor
@@ -108,3 +182,10 @@ type MethodInterceptor interface {
 	// if you want to abort the current request, just call abort() and response inside the method
 	PostHandle(ctx *gin.Context)
 }
+
+// CompletionInterceptor optional interface of the MethodInterceptor
+// AfterCompletion is triggered when the request completes, even if the method panics or
+// the request is aborted, in the reverse order of PreHandle. Use it to release the resources of PreHandle
+type CompletionInterceptor interface {
+	AfterCompletion(ctx *gin.Context)
+}
@@ -0,0 +1,95 @@
+package mvc_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	mvc.Controller
+}
+
+// Roles responds the values of the repeated @RequiresRoles
+func (r *RoleController) Roles(ctx *gin.Context) {
+	var roles []string
+	for _, anno := range mvc.GetAnnotations(ctx, "RequiresRoles") {
+		roles = append(roles, anno.Args...)
+	}
+	ctx.String(http.StatusOK, strings.Join(roles, ","))
+}
+
+// TestRepeatedAnnotations the values of a repeated annotation are joined with "\n" by the generator
+func TestRepeatedAnnotations(t *testing.T) {
+	core.Apis = map[string][]*core.MethodInfo{
+		"RoleController": {
+			{Method: http.MethodGet, ApiPath: "/roles", Name: "Roles", Annotations: map[string]string{"RequiresRoles": "(\"admin\")\n(\"ops\", \"dev\")"}},
+		},
+	}
+	h := gintest.New(t, gintest.WithController(&RoleController{}))
+	res := h.Client().Get("/roles").AssertStatus(http.StatusOK)
+	if body := res.Recorder.Body.String(); body != "admin,ops,dev" {
+		t.Fatalf("expected the roles of both annotations, got %q", body)
+	}
+	if annos := mvc.RouteAnnotations(http.MethodGet, "/roles"); len(annos) != 2 {
+		t.Fatalf("expected 2 annotations, got %d", len(annos))
+	}
+}
+
+type MixedController struct {
+	mvc.Controller
+}
+
+func (m *MixedController) Echo(ctx *gin.Context) {
+	ctx.String(http.StatusOK, scope(ctx))
+}
+
+func (m *MixedController) Get(ctx *gin.Context) {
+	ctx.String(http.StatusOK, scope(ctx))
+}
+
+func (m *MixedController) Post(ctx *gin.Context) {
+	ctx.String(http.StatusOK, scope(ctx))
+}
+
+func scope(ctx *gin.Context) string {
+	val, _ := mvc.GetAnnotation(ctx, "Scope")
+	return val
+}
+
+// TestMixedMethods the apis of @Any and of the specific methods are resolved independently
+func TestMixedMethods(t *testing.T) {
+	core.Apis = map[string][]*core.MethodInfo{
+		"MixedController": {
+			{Method: "Any", ApiPath: "/echo", Name: "Echo", Annotations: map[string]string{"Scope": "any"}},
+			{Method: http.MethodGet, ApiPath: "/user", Name: "Get", Annotations: map[string]string{"Scope": "get"}},
+			{Method: http.MethodPost, ApiPath: "/user", Name: "Post", Annotations: map[string]string{"Scope": "post"}},
+		},
+	}
+	c := gintest.New(t, gintest.WithController(&MixedController{})).Client()
+	for _, tc := range []struct{ method, path, scope string }{
+		{http.MethodGet, "/echo", "any"},
+		{http.MethodDelete, "/echo", "any"},
+		{http.MethodGet, "/user", "get"},
+		{http.MethodPost, "/user", "post"},
+	} {
+		res := c.Request(tc.method, tc.path, nil).AssertStatus(http.StatusOK)
+		if body := res.Recorder.Body.String(); body != tc.scope {
+			t.Errorf("%s %s, expected the annotation %q, got %q", tc.method, tc.path, tc.scope, body)
+		}
+	}
+	if annos := mvc.RouteAnnotations(http.MethodDelete, "/user"); annos != nil {
+		t.Errorf("expected no annotation of DELETE /user, got %v", annos)
+	}
+	if annos := mvc.HandlerAnnotations("github.com/archine/gin-plus/v3/mvc_test.MixedController.Post"); len(annos) != 1 || annos[0].Value != "post" {
+		t.Errorf("expected the annotation of the qualified handler, got %v", annos)
+	}
+	if annos := mvc.HandlerAnnotations("MixedController.Get"); len(annos) != 1 || annos[0].Value != "get" {
+		t.Errorf("expected the annotation of the handler, got %v", annos)
+	}
+}
@@ -0,0 +1,223 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/archine/gin-plus/v3/internal/intercept"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/gin-plus/v3/security"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Rate limiting interceptor, rules are declared by @RateLimit or by the rate_limit block of app.yml.
+// Example:
+//
+//	var conf ratelimit.Config
+//	_ = application.UnmarshalKey("rate_limit", &conf)
+//	app.Interceptor(ratelimit.New(conf))
+//
+//	// Login
+//	// @POST(path="/login")
+//	// @RateLimit(limit=5, window="1m", key="ip")
+//	func (u *UserController) Login(ctx *gin.Context)
+
+// AnnotationName the annotation declaring the rate limit rule of the api
+const AnnotationName = "RateLimit"
+
+// Algorithms
+const (
+	// TokenBucket allow bursts up to the burst size, refilled at limit per window
+	TokenBucket = "token_bucket"
+	// SlidingWindow allow at most limit requests in any window
+	SlidingWindow = "sliding_window"
+)
+
+// Built-in keys
+const (
+	// KeyIP limit by the client ip
+	KeyIP = "ip"
+	// KeyPrincipal limit by the current principal of the security package, anonymous requests fall back to the client ip
+	KeyPrincipal = "principal"
+)
+
+// Rule the rate limit rule
+type Rule struct {
+	Algorithm string        `mapstructure:"algorithm" anno:"algorithm"` // token_bucket (default) or sliding_window
+	Limit     int           `mapstructure:"limit" anno:"limit"`         // Permits per window
+	Window    time.Duration `mapstructure:"window" anno:"window"`       // Default 1s
+	Burst     int           `mapstructure:"burst" anno:"burst"`         // Capacity of the token bucket, default the limit
+	Key       string        `mapstructure:"key" anno:"key"`             // ip (default), principal or the name of a custom key func
+}
+
+func (r Rule) withDefaults() Rule {
+	if r.Algorithm == "" {
+		r.Algorithm = TokenBucket
+	}
+	if r.Window <= 0 {
+		r.Window = time.Second
+	}
+	if r.Burst <= 0 {
+		r.Burst = r.Limit
+	}
+	if r.Key == "" {
+		r.Key = KeyIP
+	}
+	return r
+}
+
+// RouteRule the rule of the routes matching the method and path
+type RouteRule struct {
+	Method string `mapstructure:"method"` // HTTP method, default empty means all methods
+	Path   string `mapstructure:"path"`   // Route path such as /user/:id, a trailing * matches the prefix
+	Rule   `mapstructure:",squash"`
+}
+
+// Config the rate limit configuration.
+// Example:
+//
+//	rate_limit:
+//	  code: 40029
+//	  default:
+//	    limit: 100
+//	  routes:
+//	    - path: /login
+//	      method: POST
+//	      limit: 5
+//	      window: 1m
+type Config struct {
+	Code    int         `mapstructure:"code"`    // Business code of the rejected requests, default resp.TooManyRequestsCode
+	Message string      `mapstructure:"message"` // Message of the rejected requests
+	Default *Rule       `mapstructure:"default"` // Rule of the apis without their own rule, default no limit
+	Routes  []RouteRule `mapstructure:"routes"`  // Rules by route, the annotation of the api takes precedence
+}
+
+// KeyFunc resolve the key of the request, requests with the same key share the limit
+type KeyFunc func(ctx *gin.Context) string
+
+// Store keep the state of the limits. The in-memory store limits each instance on its own,
+// so n instances allow n times the limit, a store shared by the instances makes the limit global
+type Store interface {
+	// Allow consume a permit of the key, returns the wait before the next permit is available when not allowed
+	Allow(ctx context.Context, key string, rule Rule) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Limiter the rate limit interceptor
+type Limiter struct {
+	conf     Config
+	store    Store
+	keyFuncs map[string]KeyFunc
+	rules    intercept.RouteCache[*Rule] // nil means no limit
+}
+
+// New Create a rate limiter with the in-memory store.
+// Add it after the security interceptor to limit by principal.
+func New(conf Config) *Limiter {
+	if conf.Code == 0 {
+		conf.Code = resp.TooManyRequestsCode
+	}
+	if conf.Message == "" {
+		conf.Message = "请求过于频繁，请稍后再试"
+	}
+	return &Limiter{
+		conf:  conf,
+		store: NewMemoryStore(),
+		keyFuncs: map[string]KeyFunc{
+			KeyIP: func(ctx *gin.Context) string {
+				return ctx.ClientIP()
+			},
+			KeyPrincipal: func(ctx *gin.Context) string {
+				if p, ok := security.Current(ctx); ok {
+					return "principal:" + p.ID
+				}
+				return ctx.ClientIP()
+			},
+		},
+	}
+}
+
+// Store Sets the store of the limits
+func (l *Limiter) Store(store Store) *Limiter {
+	l.store = store
+	return l
+}
+
+// KeyFunc Register a custom key func, the name is used in the key of the rule
+func (l *Limiter) KeyFunc(name string, f KeyFunc) *Limiter {
+	l.keyFuncs[name] = f
+	return l
+}
+
+func (l *Limiter) Predicate(ctx *gin.Context) bool {
+	return l.rule(ctx) != nil
+}
+
+func (l *Limiter) PreHandle(ctx *gin.Context) {
+	rule := l.rule(ctx)
+	key := fmt.Sprintf("%s %s|%s", ctx.Request.Method, ctx.FullPath(), l.keyFuncs[rule.Key](ctx))
+	allowed, retryAfter, err := l.store.Allow(ctx, key, *rule)
+	if err != nil {
+		// fail open, the availability of the api is preferred to the limit
+		logger.Log.Errorf("rate limit store error, %s", err.Error())
+		return
+	}
+	if allowed {
+		return
+	}
+	// at least one second, the header has no smaller unit
+	ctx.Header("Retry-After", strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))))
+	resp.InitResp(ctx, http.StatusTooManyRequests).WithCode(l.conf.Code).WithMessage(l.conf.Message).To()
+	ctx.Abort()
+}
+
+func (l *Limiter) PostHandle(ctx *gin.Context) {}
+
+// rule the rule of the api, resolved on the first request of the route
+func (l *Limiter) rule(ctx *gin.Context) *Rule {
+	return l.rules.Load(ctx, func() *Rule {
+		return l.resolve(ctx)
+	})
+}
+
+// resolve the rule of the api, priority: annotation > route rule > default.
+// A rule with an unknown key limits by the client ip, so that a typo never disables the limit
+func (l *Limiter) resolve(ctx *gin.Context) *Rule {
+	var rule *Rule
+	if annos := mvc.GetAnnotations(ctx, AnnotationName); len(annos) > 0 {
+		var r Rule
+		if err := annos[0].Decode(&r); err != nil {
+			logger.Log.Errorf("invalid @%s of %s, %s", AnnotationName, ctx.FullPath(), err.Error())
+		} else {
+			rule = &r
+		}
+	} else {
+		for _, rr := range l.conf.Routes {
+			if intercept.MatchRoute(rr.Method, rr.Path, ctx.Request.Method, ctx.FullPath()) {
+				r := rr.Rule
+				rule = &r
+				break
+			}
+		}
+		if rule == nil && l.conf.Default != nil {
+			r := *l.conf.Default
+			rule = &r
+		}
+	}
+	if rule != nil {
+		if rule.Limit <= 0 {
+			rule = nil
+		} else {
+			*rule = rule.withDefaults()
+			if _, ok := l.keyFuncs[rule.Key]; !ok {
+				logger.Log.Errorf("unknown rate limit key %s of %s, limit by %s instead", rule.Key, ctx.FullPath(), KeyIP)
+				rule.Key = KeyIP
+			}
+		}
+	}
+	return rule
+}
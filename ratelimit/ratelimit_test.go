@@ -0,0 +1,53 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/ratelimit"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+)
+
+type LoginController struct {
+	mvc.Controller
+}
+
+func (l *LoginController) Login(ctx *gin.Context) {
+	resp.Ok(ctx)
+}
+
+func (l *LoginController) Export(ctx *gin.Context) {
+	resp.Ok(ctx)
+}
+
+func newClient(t *testing.T) *gintest.Client {
+	core.Apis = map[string][]*core.MethodInfo{
+		"LoginController": {
+			{Method: http.MethodPost, ApiPath: "/login", Name: "Login", Annotations: map[string]string{ratelimit.AnnotationName: `(limit=1, window="1m")`}},
+			{Method: http.MethodGet, ApiPath: "/export", Name: "Export", Annotations: map[string]string{ratelimit.AnnotationName: `(limit=1, window="1m", key="tenant")`}},
+		},
+	}
+	return gintest.New(t, gintest.WithController(&LoginController{}), gintest.WithApp(func(app *application.App) {
+		app.Interceptor(ratelimit.New(ratelimit.Config{}))
+	})).Client()
+}
+
+func TestRetryAfter(t *testing.T) {
+	c := newClient(t)
+	c.Request(http.MethodPost, "/login", nil).AssertStatus(http.StatusOK).AssertCode(0)
+	c.Request(http.MethodPost, "/login", nil).
+		AssertStatus(http.StatusTooManyRequests).
+		AssertCode(resp.TooManyRequestsCode).
+		AssertHeader("Retry-After", "60")
+}
+
+func TestUnknownKeyLimitsByIP(t *testing.T) {
+	c := newClient(t)
+	c.Get("/export").AssertStatus(http.StatusOK)
+	c.Get("/export").AssertStatus(http.StatusTooManyRequests)
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore the in-memory store, the limits are per instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	idle   time.Duration // time to refill the bucket completely, the state can be dropped after it
+}
+
+// window the sliding window counter, the previous window is weighted by its overlap with the sliding window
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+	size     time.Duration
+}
+
+// NewMemoryStore Create an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		windows:   make(map[string]*window),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryStore) Allow(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	switch rule.Algorithm {
+	case TokenBucket:
+		allowed, retryAfter := m.takeToken(key, rule, now)
+		return allowed, retryAfter, nil
+	case SlidingWindow:
+		allowed, retryAfter := m.slide(key, rule, now)
+		return allowed, retryAfter, nil
+	}
+	return false, 0, fmt.Errorf("unknown rate limit algorithm %s", rule.Algorithm)
+}
+
+func (m *MemoryStore) takeToken(key string, rule Rule, now time.Time) (bool, time.Duration) {
+	rate := float64(rule.Limit) / rule.Window.Seconds() // tokens per second
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.idle = time.Duration(float64(rule.Burst) / rate * float64(time.Second))
+	b.tokens = min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+func (m *MemoryStore) slide(key string, rule Rule, now time.Time) (bool, time.Duration) {
+	w, ok := m.windows[key]
+	if !ok {
+		w = &window{start: now, size: rule.Window}
+		m.windows[key] = w
+	}
+	if elapsed := now.Sub(w.start); elapsed >= w.size {
+		// skip the empty windows between the last request and now
+		if elapsed >= 2*w.size {
+			w.previous = 0
+		} else {
+			w.previous = w.current
+		}
+		w.current = 0
+		w.start = now.Add(-(elapsed % w.size))
+	}
+	elapsed := now.Sub(w.start)
+	weight := 1 - float64(elapsed)/float64(w.size)
+	if float64(w.previous)*weight+float64(w.current) < float64(rule.Limit) {
+		w.current++
+		return true, 0
+	}
+	// wait until the weight of the previous window drops enough, or the current window ends
+	if w.previous > 0 && w.current < rule.Limit {
+		need := 1 - float64(rule.Limit-w.current)/float64(w.previous)
+		return false, time.Duration(need*float64(w.size)) - elapsed
+	}
+	return false, w.size - elapsed
+}
+
+// sweep drop the idle states once a minute
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for k, b := range m.buckets {
+		if now.Sub(b.last) > b.idle {
+			delete(m.buckets, k)
+		}
+	}
+	for k, w := range m.windows {
+		if now.Sub(w.start) > 2*w.size {
+			delete(m.windows, k)
+		}
+	}
+}
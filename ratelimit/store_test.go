@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type attempt struct {
+	at         time.Duration // since the first request
+	allowed    bool
+	retryAfter time.Duration
+}
+
+func check(t *testing.T, allow func(now time.Time) (bool, time.Duration), attempts []attempt) {
+	t.Helper()
+	start := time.Now()
+	for i, a := range attempts {
+		allowed, retryAfter := allow(start.Add(a.at))
+		if allowed != a.allowed || retryAfter != a.retryAfter {
+			t.Errorf("attempt %d at %s, expected (%t, %s), got (%t, %s)", i, a.at, a.allowed, a.retryAfter, allowed, retryAfter)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	m := NewMemoryStore()
+	rule := Rule{Limit: 2, Window: time.Second, Burst: 3}.withDefaults()
+	check(t, func(now time.Time) (bool, time.Duration) { return m.takeToken("k", rule, now) }, []attempt{
+		{0, true, 0},
+		{0, true, 0},
+		{0, true, 0}, // the burst
+		{0, false, 500 * time.Millisecond},
+		{250 * time.Millisecond, false, 250 * time.Millisecond},
+		{500 * time.Millisecond, true, 0}, // refilled at 2 per second
+		{500 * time.Millisecond, false, 500 * time.Millisecond},
+		{10 * time.Second, true, 0}, // refilled up to the burst only
+		{10 * time.Second, true, 0},
+		{10 * time.Second, true, 0},
+		{10 * time.Second, false, 500 * time.Millisecond},
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	m := NewMemoryStore()
+	rule := Rule{Algorithm: SlidingWindow, Limit: 2, Window: time.Second}.withDefaults()
+	check(t, func(now time.Time) (bool, time.Duration) { return m.slide("k", rule, now) }, []attempt{
+		{0, true, 0},
+		{0, true, 0},
+		{500 * time.Millisecond, false, 500 * time.Millisecond}, // until the window ends
+		// the previous window weighs 0.75, 2 * 0.75 < 2
+		{1250 * time.Millisecond, true, 0},
+		// 2 * 0.75 + 1 >= 2, wait until the weight drops to 0.5
+		{1250 * time.Millisecond, false, 250 * time.Millisecond},
+		{1500 * time.Millisecond, false, 0},
+		{1600 * time.Millisecond, true, 0},
+		// the windows between are empty, the previous one is dropped
+		{5 * time.Second, true, 0},
+		{5 * time.Second, true, 0},
+		{5 * time.Second, false, time.Second},
+	})
+}
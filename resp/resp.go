@@ -16,8 +16,11 @@ const (
 	NonLoginCode        = 40001
 	TokenExpiredCode    = 40002
 	ForbiddenCode       = 40003
+	ConflictCode        = 40009
 	ParamValidationCode = 40010
+	TooManyRequestsCode = 40029
 	SystemErrorCode     = 50000
+	TimeoutCode         = 50004
 )
 
 type Resp interface {
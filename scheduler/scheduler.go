@@ -0,0 +1,297 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/exception"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/ioc"
+	"github.com/robfig/cron/v3"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Background task scheduler tied to the application lifecycle.
+// Example:
+//
+//	s := scheduler.New()
+//	s.FixedRate("report", time.Minute, func(ctx context.Context) error { ... })
+//	s.Register(&jobs.CleanJob{}) // methods annotated with @Scheduled
+//	application.Default().Bean(s).Run()
+
+// AnnotationName the annotation declaring a scheduled bean method, the method signature must be
+// func(ctx context.Context) error, func(ctx context.Context) or func().
+// Example:
+//
+//	// Clean
+//	// @Scheduled(cron="0 */5 * * * *")
+//	func (c *CleanJob) Clean(ctx context.Context) error
+//
+//	// @Scheduled(fixed_rate="10s", initial_delay="5s")
+//	// @Scheduled(fixed_delay="1m")
+const AnnotationName = "Scheduled"
+
+// Task the scheduled work, the ctx is cancelled when the application shuts down
+type Task func(ctx context.Context) error
+
+// Job the definition of a scheduled task, exactly one of Cron, FixedRate and FixedDelay must be set
+type Job struct {
+	Name         string
+	Cron         string        // Cron expression, seconds are optional, descriptors such as @every 1m and @daily are supported
+	FixedRate    time.Duration // Run at the fixed interval measured from the start of each run
+	FixedDelay   time.Duration // Run at the fixed interval measured from the end of each run
+	InitialDelay time.Duration // Delay of the first run, not applicable to cron
+	Task         Task
+}
+
+// cronParser accept expressions with or without the seconds field
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+type job struct {
+	Job
+	schedule cron.Schedule
+	running  atomic.Bool
+}
+
+// Scheduler run jobs in the background, it is a SmartLifecycle bean started and stopped with the application
+type Scheduler struct {
+	mu     sync.Mutex
+	jobs   []*job
+	beans  []any
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New create a scheduler, add it to the application with App.Bean
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Schedule add a job, jobs added after the scheduler is started are not run
+func (s *Scheduler) Schedule(j Job) error {
+	if j.Task == nil {
+		return fmt.Errorf("job %s task cannot be null", j.Name)
+	}
+	sj := &job{Job: j}
+	set := 0
+	if j.Cron != "" {
+		schedule, err := cronParser.Parse(j.Cron)
+		if err != nil {
+			return fmt.Errorf("job %s invalid cron expression, %w", j.Name, err)
+		}
+		sj.schedule = schedule
+		set++
+	}
+	if j.FixedRate > 0 {
+		set++
+	}
+	if j.FixedDelay > 0 {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("job %s must specify exactly one of cron, fixed_rate and fixed_delay", j.Name)
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, sj)
+	s.mu.Unlock()
+	return nil
+}
+
+// Cron add a job triggered by the cron expression
+func (s *Scheduler) Cron(name, spec string, task Task) error {
+	return s.Schedule(Job{Name: name, Cron: spec, Task: task})
+}
+
+// FixedRate add a job running at the fixed interval, a run is skipped if the previous one is still running
+func (s *Scheduler) FixedRate(name string, interval time.Duration, task Task) error {
+	return s.Schedule(Job{Name: name, FixedRate: interval, Task: task})
+}
+
+// FixedDelay add a job running with the fixed delay between the end of a run and the start of the next one
+func (s *Scheduler) FixedDelay(name string, delay time.Duration, task Task) error {
+	return s.Schedule(Job{Name: name, FixedDelay: delay, Task: task})
+}
+
+// Register add the methods annotated with @Scheduled of the beans, the beans must be pointers.
+// The metadata is generated by gp-ast in the same way as controllers, call it before the application runs.
+// The beans are injected when the scheduler starts.
+func (s *Scheduler) Register(beans ...any) error {
+	for _, bean := range beans {
+		beanType := reflect.TypeOf(bean)
+		if beanType.Kind() != reflect.Pointer {
+			return errors.New("scheduled bean must be a pointer")
+		}
+		for _, m := range core.Apis[beanType.Elem().Name()] {
+			anno, ok := m.Annotations[AnnotationName]
+			if !ok {
+				continue
+			}
+			name := beanType.Elem().Name() + "." + m.Name
+			task, err := methodTask(bean, m.Name)
+			if err != nil {
+				return fmt.Errorf("job %s, %w", name, err)
+			}
+			j, err := parseAnnotation(anno)
+			if err != nil {
+				return fmt.Errorf("job %s, %w", name, err)
+			}
+			j.Name, j.Task = name, task
+			if err = s.Schedule(j); err != nil {
+				return err
+			}
+		}
+		s.beans = append(s.beans, bean)
+	}
+	return nil
+}
+
+// Start run all jobs in the background
+func (s *Scheduler) Start(ctx context.Context) error {
+	for _, bean := range s.beans {
+		ioc.Inject(bean)
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(runCtx, j)
+	}
+	logger.Log.Debugf("Scheduler started with %d jobs", len(s.jobs))
+	return nil
+}
+
+// Stop cancel the ctx of the jobs and wait for the running ones until the ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler stop timeout, some jobs are still running, %w", ctx.Err())
+	}
+}
+
+// Phase the scheduler starts after the other lifecycle beans and stops before them
+func (s *Scheduler) Phase() int {
+	return 1 << 20
+}
+
+// loop trigger the job until the ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+	delay := j.InitialDelay
+	for {
+		if j.schedule != nil {
+			now := time.Now()
+			delay = j.schedule.Next(now).Sub(now)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if j.FixedDelay > 0 {
+			s.run(ctx, j)
+			delay = j.FixedDelay
+			continue
+		}
+		// cron and fixed rate triggers never wait for the run, overlapping runs are skipped
+		if !j.running.CompareAndSwap(false, true) {
+			logger.Log.Debugf("Job %s skipped, the previous run is still running", j.Name)
+		} else {
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer j.running.Store(false)
+				s.run(ctx, j)
+			}()
+		}
+		delay = j.FixedRate
+	}
+}
+
+// run the job once, panics are recovered and reported through the exception package
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Errorf("Job %s panic", j.Name)
+			exception.Report(r)
+		}
+	}()
+	if err := j.Task(ctx); err != nil {
+		logger.Log.Errorf("Job %s failed, %s", j.Name, err.Error())
+	}
+}
+
+// methodTask adapt the bean method to a task
+func methodTask(bean any, name string) (Task, error) {
+	method := reflect.ValueOf(bean).MethodByName(name)
+	if !method.IsValid() {
+		return nil, fmt.Errorf("method %s not found", name)
+	}
+	switch f := method.Interface().(type) {
+	case func(context.Context) error:
+		return f, nil
+	case func(context.Context):
+		return func(ctx context.Context) error {
+			f(ctx)
+			return nil
+		}, nil
+	case func():
+		return func(context.Context) error {
+			f()
+			return nil
+		}, nil
+	}
+	return nil, errors.New("scheduled method must be func(ctx context.Context) error, func(ctx context.Context) or func()")
+}
+
+// scheduledAnnotation the parameters of @Scheduled
+type scheduledAnnotation struct {
+	Value        string        `anno:"value"`
+	Cron         string        `anno:"cron"`
+	FixedRate    time.Duration `anno:"fixed_rate"`
+	FixedDelay   time.Duration `anno:"fixed_delay"`
+	InitialDelay time.Duration `anno:"initial_delay"`
+}
+
+// parseAnnotation parse the annotation value such as cron="0 * * * * *" or fixed_rate="10s", initial_delay="1s".
+// A single quoted value is treated as a cron expression.
+func parseAnnotation(value string) (Job, error) {
+	anno, err := mvc.ParseAnnotation(AnnotationName, value)
+	if err != nil {
+		return Job{}, err
+	}
+	for k := range anno.Params {
+		switch k {
+		case "cron", "fixed_rate", "fixed_delay", "initial_delay":
+		default:
+			return Job{}, fmt.Errorf("unknown @Scheduled parameter %s", k)
+		}
+	}
+	var sa scheduledAnnotation
+	if err = anno.Decode(&sa); err != nil {
+		return Job{}, err
+	}
+	if sa.Cron == "" {
+		sa.Cron = sa.Value
+	}
+	return Job{Cron: sa.Cron, FixedRate: sa.FixedRate, FixedDelay: sa.FixedDelay, InitialDelay: sa.InitialDelay}, nil
+}
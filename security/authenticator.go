@@ -0,0 +1,249 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyAuthenticator authenticate the request by the api key in the header or the query
+type APIKeyAuthenticator struct {
+	Header string                // Header carrying the key, default X-API-Key
+	Query  string                // Query parameter carrying the key, default empty means not allowed
+	Keys   map[string]*Principal // Static keys
+	// Lookup resolve the keys that are not in Keys, return nil principal for an unknown key
+	Lookup func(ctx context.Context, key string) (*Principal, error)
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx *gin.Context) (*Principal, error) {
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	key := ctx.GetHeader(header)
+	if key == "" && a.Query != "" {
+		key = ctx.Query(a.Query)
+	}
+	if key == "" {
+		return nil, nil
+	}
+	if p, ok := a.Keys[key]; ok {
+		return p, nil
+	}
+	if a.Lookup != nil {
+		p, err := a.Lookup(ctx, key)
+		if err != nil || p != nil {
+			return p, err
+		}
+	}
+	return nil, fmt.Errorf("invalid api key, %w", ErrUnauthenticated)
+}
+
+// SessionStore store the principal of the sessions
+type SessionStore interface {
+	// Get returns nil principal when the session does not exist or is expired
+	Get(ctx context.Context, id string) (*Principal, error)
+	Save(ctx context.Context, id string, principal *Principal, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionAuthenticator authenticate the request by the session id in the cookie
+type SessionAuthenticator struct {
+	Cookie string        // Cookie name, default SESSION
+	TTL    time.Duration // Session lifetime, default 30m
+	Store  SessionStore
+}
+
+func (s *SessionAuthenticator) Authenticate(ctx *gin.Context) (*Principal, error) {
+	id, err := ctx.Cookie(s.cookieName())
+	if err != nil || id == "" {
+		return nil, nil
+	}
+	p, err := s.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("session not found, %w", ErrCredentialsExpired)
+	}
+	return p, nil
+}
+
+// Login create a session for the principal and set the cookie
+func (s *SessionAuthenticator) Login(ctx *gin.Context, principal *Principal) error {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	id := hex.EncodeToString(buf)
+	if err := s.Store.Save(ctx, id, principal, s.ttl()); err != nil {
+		return err
+	}
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(s.cookieName(), id, int(s.ttl().Seconds()), "/", "", ctx.Request.TLS != nil, true)
+	return nil
+}
+
+// Logout delete the session and the cookie
+func (s *SessionAuthenticator) Logout(ctx *gin.Context) error {
+	id, err := ctx.Cookie(s.cookieName())
+	if err != nil || id == "" {
+		return nil
+	}
+	ctx.SetCookie(s.cookieName(), "", -1, "/", "", ctx.Request.TLS != nil, true)
+	return s.Store.Delete(ctx, id)
+}
+
+func (s *SessionAuthenticator) cookieName() string {
+	if s.Cookie == "" {
+		return "SESSION"
+	}
+	return s.Cookie
+}
+
+func (s *SessionAuthenticator) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return 30 * time.Minute
+	}
+	return s.TTL
+}
+
+// MemorySessionStore the in-memory session store, sessions are lost when the application restarts
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	principal *Principal
+	expireAt  time.Time
+}
+
+func (m *MemorySessionStore) Get(ctx context.Context, id string) (*Principal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(s.expireAt) {
+		delete(m.sessions, id)
+		return nil, nil
+	}
+	return s.principal, nil
+}
+
+func (m *MemorySessionStore) Save(ctx context.Context, id string, principal *Principal, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions == nil {
+		m.sessions = make(map[string]memorySession)
+	}
+	now := time.Now()
+	for k, s := range m.sessions {
+		if now.After(s.expireAt) {
+			delete(m.sessions, k)
+		}
+	}
+	m.sessions[id] = memorySession{principal: principal, expireAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// JWTAuthenticator authenticate the request by the bearer token in the Authorization header, signed by local keys.
+// By default the sub claim is the principal id, the name, roles and permissions claims are mapped to the principal.
+// Only access tokens are accepted, so the refresh tokens of TokenService cannot be used to call the apis.
+// Use TokenService to issue tokens and verify them with rotated keys.
+type JWTAuthenticator struct {
+	Key        any            // Verification key, []byte for HS, *rsa.PublicKey for RS, *ecdsa.PublicKey for ES
+	Keys       map[string]any // Verification keys by the kid header, takes precedence over Key
+	Algorithms []string       // Accepted signing algorithms, default HS256
+	Issuer     string         // Expected iss claim, default not checked
+	Audience   string         // Expected aud claim, default not checked
+	TokenType  string         // Expected typ claim, default access, "-" disables the check for the tokens of other issuers
+	Revocation RevocationList // Revoked tokens by the jti claim, default not checked
+	Leeway     time.Duration  // Allowed clock skew
+	Mapping    ClaimsMapping  // Mapping between the claims and the principal
+}
+
+func (j *JWTAuthenticator) Authenticate(ctx *gin.Context) (*Principal, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, nil
+	}
+	algorithms := j.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"HS256"}
+	}
+	options := []jwt.ParserOption{jwt.WithValidMethods(algorithms), jwt.WithLeeway(j.Leeway)}
+	if j.Issuer != "" {
+		options = append(options, jwt.WithIssuer(j.Issuer))
+	}
+	if j.Audience != "" {
+		options = append(options, jwt.WithAudience(j.Audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, j.keyFunc, options...)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w, %w", ErrCredentialsExpired, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, %w", ErrUnauthenticated, err)
+	}
+	typ := j.TokenType
+	if typ == "" {
+		typ = AccessToken
+	}
+	if actual, _ := claims["typ"].(string); typ != "-" && actual != typ {
+		return nil, fmt.Errorf("%w, %s token expected", ErrUnauthenticated, typ)
+	}
+	if err = checkRevoked(ctx, j.Revocation, claims); err != nil {
+		return nil, err
+	}
+	return j.Mapping.Principal(claims), nil
+}
+
+func (j *JWTAuthenticator) keyFunc(token *jwt.Token) (any, error) {
+	if len(j.Keys) > 0 {
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := j.Keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if j.Key == nil {
+		return nil, errors.New("no verification key")
+	}
+	return j.Key, nil
+}
+
+// stringsClaim accept an array or a space separated string
+func stringsClaim(v any) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []any:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
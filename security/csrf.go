@@ -0,0 +1,193 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CsrfExemptAnnotation disable the csrf check of the api, such as webhooks authenticated by signatures
+const CsrfExemptAnnotation = "CsrfExempt"
+
+// CSRF modes
+const (
+	// DoubleSubmit the token is kept in a cookie readable by scripts, the client echoes it in the header or the form
+	DoubleSubmit = "double_submit"
+	// Synchronizer the token is kept on the server and bound to the session cookie
+	Synchronizer = "synchronizer"
+)
+
+// csrfTokenKey the key of the token in the gin context
+const csrfTokenKey = "gin-plus/security/csrf"
+
+// CSRFConfig the csrf protection configuration.
+// Requests without any cookie are not checked, they carry no ambient credentials to forge.
+type CSRFConfig struct {
+	Mode          string `mapstructure:"mode"`           // double_submit (default) or synchronizer
+	CookieName    string `mapstructure:"cookie_name"`    // Token cookie of the double submit mode, default XSRF-TOKEN
+	HeaderName    string `mapstructure:"header_name"`    // Header carrying the token, default X-XSRF-TOKEN
+	FormField     string `mapstructure:"form_field"`     // Form field carrying the token, default _csrf
+	SessionCookie string `mapstructure:"session_cookie"` // Session cookie of the synchronizer mode, default SESSION
+	SameSite      string `mapstructure:"same_site"`      // SameSite of the token cookie, lax (default), strict or none
+}
+
+// CSRFStore keep the tokens of the synchronizer mode by session
+type CSRFStore interface {
+	// Get returns empty when the session has no token
+	Get(ctx context.Context, session string) (string, error)
+	Save(ctx context.Context, session string, token string) error
+}
+
+// CSRF the interceptor protecting the unsafe methods against cross-site request forgery
+type CSRF struct {
+	conf     CSRFConfig
+	sameSite http.SameSite
+	store    CSRFStore
+}
+
+// NewCSRF Create the csrf interceptor, the synchronizer mode uses the in-memory store by default
+func NewCSRF(conf CSRFConfig) *CSRF {
+	if conf.Mode == "" {
+		conf.Mode = DoubleSubmit
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = "XSRF-TOKEN"
+	}
+	if conf.HeaderName == "" {
+		conf.HeaderName = "X-XSRF-TOKEN"
+	}
+	if conf.FormField == "" {
+		conf.FormField = "_csrf"
+	}
+	if conf.SessionCookie == "" {
+		conf.SessionCookie = "SESSION"
+	}
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(conf.SameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+	return &CSRF{conf: conf, sameSite: sameSite, store: &MemoryCSRFStore{}}
+}
+
+// Store Sets the token store of the synchronizer mode, every instance serving the session must read the same token
+func (c *CSRF) Store(store CSRFStore) *CSRF {
+	c.store = store
+	return c
+}
+
+func (c *CSRF) Predicate(ctx *gin.Context) bool {
+	_, exempt := mvc.GetAnnotation(ctx, CsrfExemptAnnotation)
+	return !exempt
+}
+
+func (c *CSRF) PreHandle(ctx *gin.Context) {
+	expected, err := c.token(ctx)
+	if err != nil {
+		logger.Log.Errorf("load csrf token error, %s", err.Error())
+		resp.SeverError(ctx, true)
+		ctx.Abort()
+		return
+	}
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return
+	}
+	if len(ctx.Request.Cookies()) == 0 {
+		return
+	}
+	actual := ctx.GetHeader(c.conf.HeaderName)
+	if actual == "" {
+		actual = ctx.PostForm(c.conf.FormField)
+	}
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+		resp.Forbidden(ctx, true, "CSRF Token 校验失败")
+		ctx.Abort()
+	}
+}
+
+func (c *CSRF) PostHandle(ctx *gin.Context) {}
+
+// token returns the expected token of the request, a new one is issued for the safe methods when absent
+func (c *CSRF) token(ctx *gin.Context) (string, error) {
+	safe := ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead
+	var token string
+	if c.conf.Mode == Synchronizer {
+		session, err := ctx.Cookie(c.conf.SessionCookie)
+		if err != nil || session == "" {
+			return "", nil
+		}
+		if token, err = c.store.Get(ctx, session); err != nil {
+			return "", err
+		}
+		if token == "" && safe {
+			if token, err = newCSRFToken(); err != nil {
+				return "", err
+			}
+			if err = c.store.Save(ctx, session, token); err != nil {
+				return "", err
+			}
+		}
+	} else {
+		token, _ = ctx.Cookie(c.conf.CookieName)
+		if token == "" && safe {
+			var err error
+			if token, err = newCSRFToken(); err != nil {
+				return "", err
+			}
+			ctx.SetSameSite(c.sameSite)
+			// readable by scripts so that the client can echo it
+			ctx.SetCookie(c.conf.CookieName, token, 0, "/", "", isHTTPS(ctx.Request), false)
+		}
+	}
+	if token != "" {
+		ctx.Set(csrfTokenKey, token)
+	}
+	return token, nil
+}
+
+// CSRFToken Gets the csrf token of the request, render it in forms or responses of the synchronizer mode
+func CSRFToken(ctx *gin.Context) string {
+	return ctx.GetString(csrfTokenKey)
+}
+
+func newCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate csrf token error, %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// MemoryCSRFStore the in-memory token store of the synchronizer mode.
+// Tokens are not expired, delete them with the sessions.
+type MemoryCSRFStore struct {
+	tokens sync.Map
+}
+
+func (m *MemoryCSRFStore) Get(ctx context.Context, session string) (string, error) {
+	token, _ := m.tokens.Load(session)
+	s, _ := token.(string)
+	return s, nil
+}
+
+func (m *MemoryCSRFStore) Save(ctx context.Context, session string, token string) error {
+	m.tokens.Store(session, token)
+	return nil
+}
+
+// Delete the token of the session
+func (m *MemoryCSRFStore) Delete(session string) {
+	m.tokens.Delete(session)
+}
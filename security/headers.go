@@ -0,0 +1,100 @@
+package security
+
+import (
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// HeadersAnnotation override the security headers of the api, such as @SecurityHeaders(frame_options="SAMEORIGIN")
+const HeadersAnnotation = "SecurityHeaders"
+
+// HeadersConfig the security response headers, empty uses the default value and "-" disables the header.
+// Example:
+//
+//	security:
+//	  headers:
+//	    content_security_policy: "default-src 'self'"
+//	    frame_options: SAMEORIGIN
+//	    custom:
+//	      Permissions-Policy: "geolocation=()"
+type HeadersConfig struct {
+	HSTS                  string            `mapstructure:"hsts" anno:"hsts"`                                       // Strict-Transport-Security, only sent over https, default max-age=15552000; includeSubDomains
+	ContentTypeOptions    string            `mapstructure:"content_type_options" anno:"content_type_options"`       // X-Content-Type-Options, default nosniff
+	FrameOptions          string            `mapstructure:"frame_options" anno:"frame_options"`                     // X-Frame-Options, default DENY
+	ContentSecurityPolicy string            `mapstructure:"content_security_policy" anno:"content_security_policy"` // Content-Security-Policy, default not sent
+	ReferrerPolicy        string            `mapstructure:"referrer_policy" anno:"referrer_policy"`                 // Referrer-Policy, default strict-origin-when-cross-origin
+	Custom                map[string]string `mapstructure:"custom"`                                                 // Extra headers
+}
+
+// merge override the fields set in the other config
+func (c HeadersConfig) merge(other HeadersConfig) HeadersConfig {
+	for _, f := range []struct{ dst, src *string }{
+		{&c.HSTS, &other.HSTS},
+		{&c.ContentTypeOptions, &other.ContentTypeOptions},
+		{&c.FrameOptions, &other.FrameOptions},
+		{&c.ContentSecurityPolicy, &other.ContentSecurityPolicy},
+		{&c.ReferrerPolicy, &other.ReferrerPolicy},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	return c
+}
+
+// Headers the interceptor writing the security headers before the api is invoked
+type Headers struct {
+	conf HeadersConfig
+}
+
+// NewHeaders Create the security headers interceptor
+func NewHeaders(conf HeadersConfig) *Headers {
+	return &Headers{conf: HeadersConfig{
+		HSTS:               "max-age=15552000; includeSubDomains",
+		ContentTypeOptions: "nosniff",
+		FrameOptions:       "DENY",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+		Custom:             conf.Custom,
+	}.merge(conf)}
+}
+
+func (h *Headers) Predicate(ctx *gin.Context) bool {
+	return true
+}
+
+func (h *Headers) PreHandle(ctx *gin.Context) {
+	conf := h.conf
+	if annos := mvc.GetAnnotations(ctx, HeadersAnnotation); len(annos) > 0 {
+		var override HeadersConfig
+		if err := annos[0].Decode(&override); err != nil {
+			logger.Log.Errorf("invalid @%s of %s, %s", HeadersAnnotation, ctx.FullPath(), err.Error())
+		}
+		conf = conf.merge(override)
+	}
+	header := ctx.Writer.Header()
+	set := func(name, value string) {
+		if value != "" && value != "-" {
+			header.Set(name, value)
+		}
+	}
+	if isHTTPS(ctx.Request) {
+		set("Strict-Transport-Security", conf.HSTS)
+	}
+	set("X-Content-Type-Options", conf.ContentTypeOptions)
+	set("X-Frame-Options", conf.FrameOptions)
+	set("Content-Security-Policy", conf.ContentSecurityPolicy)
+	set("Referrer-Policy", conf.ReferrerPolicy)
+	for name, value := range conf.Custom {
+		set(name, value)
+	}
+}
+
+func (h *Headers) PostHandle(ctx *gin.Context) {}
+
+// isHTTPS whether the request is served over https, directly or behind a proxy terminating tls
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,253 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/archine/gin-plus/v3/internal/filewatch"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"sync"
+)
+
+// Key a signing or verification key of the key set
+type Key struct {
+	ID         string // Key id, the kid header of the token
+	Algorithm  string // Signing algorithm such as HS256, RS256 or ES256
+	Key        any    // Verification key, []byte for HS, *rsa.PublicKey for RS, *ecdsa.PublicKey for ES
+	SigningKey any    // Signing key, []byte for HS, *rsa.PrivateKey for RS, *ecdsa.PrivateKey for ES, nil means verification only
+}
+
+// KeySet the keys used to sign and verify tokens.
+// Keys can be rotated through a JWKS file: the first key with private material is used to sign new tokens,
+// the others are kept to verify the tokens issued before the rotation.
+type KeySet struct {
+	mu       sync.RWMutex
+	static   []*Key
+	jwks     []*Key
+	jwksFile string
+	watcher  *filewatch.Watcher
+}
+
+// NewKeySet Create a key set with static keys, the first one with a signing key is used to sign tokens
+func NewKeySet(keys ...Key) *KeySet {
+	ks := &KeySet{}
+	for _, k := range keys {
+		k := k
+		if k.SigningKey == nil {
+			if secret, ok := k.Key.([]byte); ok {
+				k.SigningKey = secret
+			}
+		}
+		ks.static = append(ks.static, &k)
+	}
+	return ks
+}
+
+// LoadJWKS load the keys of a JWKS file, they take precedence over the static keys.
+// When watch is true the file is reloaded on any change of its directory, call Close to stop watching.
+func (k *KeySet) LoadJWKS(file string, watch bool) error {
+	k.jwksFile = file
+	if err := k.reload(); err != nil {
+		return err
+	}
+	if !watch {
+		return nil
+	}
+	watcher, err := filewatch.Watch("jwks file", []string{file}, k.reload)
+	if err != nil {
+		return err
+	}
+	k.watcher = watcher
+	return nil
+}
+
+// Close stop watching the JWKS file
+func (k *KeySet) Close() error {
+	if k.watcher != nil {
+		return k.watcher.Close()
+	}
+	return nil
+}
+
+func (k *KeySet) reload() error {
+	content, err := os.ReadFile(k.jwksFile)
+	if err != nil {
+		return fmt.Errorf("read jwks file error, %w", err)
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return fmt.Errorf("parse jwks file error, %w", err)
+	}
+	k.mu.Lock()
+	k.jwks = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// signing the key used to sign new tokens
+func (k *KeySet) signing() (*Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, keys := range [][]*Key{k.jwks, k.static} {
+		for _, key := range keys {
+			if key.SigningKey != nil {
+				return key, nil
+			}
+		}
+	}
+	return nil, errors.New("no signing key")
+}
+
+// keyFunc find the verification key by the kid header, a token without kid is verified by the signing key
+func (k *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	var key *Key
+	if kid == "" {
+		key, _ = k.signing()
+	} else {
+		k.mu.RLock()
+		for _, keys := range [][]*Key{k.jwks, k.static} {
+			for _, candidate := range keys {
+				if key == nil && candidate.ID == kid {
+					key = candidate
+				}
+			}
+		}
+		k.mu.RUnlock()
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if key.Algorithm != "" && key.Algorithm != token.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing algorithm %s of key %q", token.Method.Alg(), kid)
+	}
+	return key.Key, nil
+}
+
+// algorithms the algorithms of all keys, used to restrict the accepted signing methods
+func (k *KeySet) algorithms() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	seen := make(map[string]bool)
+	var algorithms []string
+	for _, keys := range [][]*Key{k.jwks, k.static} {
+		for _, key := range keys {
+			if alg := key.Algorithm; alg != "" && !seen[alg] {
+				seen[alg] = true
+				algorithms = append(algorithms, alg)
+			}
+		}
+	}
+	return algorithms
+}
+
+// jwk a JSON web key, see RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+}
+
+func parseJWKS(content []byte) ([]*Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		key, err := j.key()
+		if err != nil {
+			return nil, fmt.Errorf("key %q, %w", j.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (j jwk) key() (*Key, error) {
+	key := &Key{ID: j.Kid, Algorithm: j.Alg}
+	switch j.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(j.K)
+		if err != nil {
+			return nil, err
+		}
+		key.Key, key.SigningKey = secret, secret
+	case "RSA":
+		n, e := decodeBigInt(j.N), decodeBigInt(j.E)
+		if n == nil || e == nil {
+			return nil, errors.New("invalid rsa key")
+		}
+		pub := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		key.Key = pub
+		if j.D != "" {
+			d, p, q := decodeBigInt(j.D), decodeBigInt(j.P), decodeBigInt(j.Q)
+			if d == nil || p == nil || q == nil {
+				return nil, errors.New("invalid rsa private key")
+			}
+			private := &rsa.PrivateKey{PublicKey: *pub, D: d, Primes: []*big.Int{p, q}}
+			if err := private.Validate(); err != nil {
+				return nil, err
+			}
+			private.Precompute()
+			key.SigningKey = private
+		}
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, y := decodeBigInt(j.X), decodeBigInt(j.Y)
+		if x == nil || y == nil {
+			return nil, errors.New("invalid ec key")
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		key.Key = pub
+		if j.D != "" {
+			d := decodeBigInt(j.D)
+			if d == nil {
+				return nil, errors.New("invalid ec private key")
+			}
+			key.SigningKey = &ecdsa.PrivateKey{PublicKey: *pub, D: d}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+	return key, nil
+}
+
+func decodeBigInt(s string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,242 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"slices"
+	"strings"
+)
+
+// Declarative authentication and authorization enforced by an interceptor.
+// Example:
+//
+//	application.Default().Interceptor(
+//	    security.New(&security.JWTAuthenticator{Key: []byte("secret")}).PermissionProvider(provider),
+//	).Run()
+//
+//	// Delete
+//	// @DELETE(path="/user/:id")
+//	// @RequiresRoles("admin", "ops", logical="any")
+//	// @RequiresPermissions("user:delete")
+//	func (u *UserController) Delete(ctx *gin.Context) {
+//	    principal, _ := security.Current(ctx)
+//	}
+
+const (
+	// AuthenticatedAnnotation the api requires an authenticated principal
+	AuthenticatedAnnotation = "Authenticated"
+	// RequiresRolesAnnotation the api requires the roles, all of them unless logical="any".
+	// Declare the roles in one annotation such as @RequiresRoles("admin", "ops"), the generator of core.Apis
+	// may keep only the last of repeated annotations
+	RequiresRolesAnnotation = "RequiresRoles"
+	// RequiresPermissionsAnnotation the api requires the permissions, all of them unless logical="any".
+	// Declare the permissions in one annotation in the same way as @RequiresRoles
+	RequiresPermissionsAnnotation = "RequiresPermissions"
+)
+
+var (
+	// ErrUnauthenticated the credentials are invalid
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrCredentialsExpired the credentials are valid but expired, the client responds resp.LoginExpired
+	ErrCredentialsExpired = errors.New("credentials expired")
+)
+
+// Principal the authenticated user
+type Principal struct {
+	ID          string
+	Name        string
+	Roles       []string
+	Permissions []string
+	Attributes  map[string]any // Extra information such as the token claims
+}
+
+// HasRole whether the principal has the role
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasPermission whether the principal has the permission.
+// A granted permission ending with "*" implies all permissions with its prefix, such as user:* implies user:write.
+func (p *Principal) HasPermission(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission || strings.HasSuffix(granted, "*") && strings.HasPrefix(permission, granted[:len(granted)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator resolve the principal from the request
+type Authenticator interface {
+	// Authenticate returns nil principal and nil error when the request carries no credentials of this kind,
+	// so that the next authenticator is tried. Wrap ErrCredentialsExpired when the credentials are expired.
+	Authenticate(ctx *gin.Context) (*Principal, error)
+}
+
+// PermissionProvider load the roles and permissions of the principal, such as from the database.
+// It is only called for apis declaring @RequiresRoles or @RequiresPermissions.
+type PermissionProvider interface {
+	Load(ctx context.Context, principal *Principal) (roles []string, permissions []string, err error)
+}
+
+// requirement the parameters of @RequiresRoles and @RequiresPermissions
+type requirement struct {
+	Value   []string `anno:"value"`
+	Logical string   `anno:"logical"` // all (default) or any
+}
+
+// satisfied whether the check passes for the values of the requirement
+func (r requirement) satisfied(check func(string) bool) bool {
+	if strings.EqualFold(r.Logical, "any") {
+		return slices.ContainsFunc(r.Value, check)
+	}
+	for _, v := range r.Value {
+		if !check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+type principalKey struct{}
+
+// principalCtxKey the key of the principal in the gin context
+const principalCtxKey = "gin-plus/security/principal"
+
+// Current Gets the principal of the request, the ctx can be the *gin.Context or the context of the request
+func Current(ctx context.Context) (*Principal, bool) {
+	if c, ok := ctx.(*gin.Context); ok {
+		if p, exists := c.Get(principalCtxKey); exists {
+			return p.(*Principal), true
+		}
+		return nil, false
+	}
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// WithPrincipal Sets the principal of the request, it is also visible to the context of the request
+func WithPrincipal(ctx *gin.Context, principal *Principal) {
+	ctx.Set(principalCtxKey, principal)
+	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), principalKey{}, principal))
+}
+
+// Interceptor the method interceptor enforcing the security annotations.
+// Every request is authenticated when it carries credentials, so that the principal is also available to public apis.
+type Interceptor struct {
+	authenticators []Authenticator
+	provider       PermissionProvider
+}
+
+// New Create a security interceptor, the authenticators are tried in order until one returns a principal
+func New(authenticators ...Authenticator) *Interceptor {
+	return &Interceptor{authenticators: authenticators}
+}
+
+// PermissionProvider Sets the provider loading the roles and permissions, by default the ones of the authenticator are used
+func (i *Interceptor) PermissionProvider(provider PermissionProvider) *Interceptor {
+	i.provider = provider
+	return i
+}
+
+func (i *Interceptor) Predicate(ctx *gin.Context) bool {
+	return true
+}
+
+func (i *Interceptor) PreHandle(ctx *gin.Context) {
+	roles, err := requirements(ctx, RequiresRolesAnnotation)
+	if err != nil {
+		logger.Log.Errorf("invalid @%s of %s, %s", RequiresRolesAnnotation, ctx.FullPath(), err.Error())
+		resp.SeverError(ctx, true)
+		ctx.Abort()
+		return
+	}
+	permissions, err := requirements(ctx, RequiresPermissionsAnnotation)
+	if err != nil {
+		logger.Log.Errorf("invalid @%s of %s, %s", RequiresPermissionsAnnotation, ctx.FullPath(), err.Error())
+		resp.SeverError(ctx, true)
+		ctx.Abort()
+		return
+	}
+	_, authenticated := mvc.GetAnnotation(ctx, AuthenticatedAnnotation)
+	required := authenticated || len(roles) > 0 || len(permissions) > 0
+	principal, err := i.authenticate(ctx)
+	if err != nil {
+		if !required {
+			// public apis ignore invalid credentials
+			logger.Log.Debugf("Authenticate %s failed, %s", ctx.FullPath(), err.Error())
+			return
+		}
+		if errors.Is(err, ErrCredentialsExpired) {
+			resp.LoginExpired(ctx, true)
+		} else {
+			resp.NoLogin(ctx, true)
+		}
+		ctx.Abort()
+		return
+	}
+	if principal == nil {
+		if required {
+			resp.NoLogin(ctx, true)
+			ctx.Abort()
+		}
+		return
+	}
+	if i.provider != nil && (len(roles) > 0 || len(permissions) > 0) {
+		// copy the principal, the authenticator may share it between requests
+		loaded := *principal
+		if loaded.Roles, loaded.Permissions, err = i.provider.Load(ctx, principal); err != nil {
+			logger.Log.Errorf("Load permissions of %s error, %s", principal.ID, err.Error())
+			resp.SeverError(ctx, true)
+			ctx.Abort()
+			return
+		}
+		principal = &loaded
+	}
+	WithPrincipal(ctx, principal)
+	for _, r := range roles {
+		if !r.satisfied(principal.HasRole) {
+			resp.Forbidden(ctx, true)
+			ctx.Abort()
+			return
+		}
+	}
+	for _, r := range permissions {
+		if !r.satisfied(principal.HasPermission) {
+			resp.Forbidden(ctx, true)
+			ctx.Abort()
+			return
+		}
+	}
+}
+
+func (i *Interceptor) PostHandle(ctx *gin.Context) {}
+
+// authenticate try the authenticators in order
+func (i *Interceptor) authenticate(ctx *gin.Context) (*Principal, error) {
+	for _, authenticator := range i.authenticators {
+		principal, err := authenticator.Authenticate(ctx)
+		if err != nil || principal != nil {
+			return principal, err
+		}
+	}
+	return nil, nil
+}
+
+// requirements decode all annotations of the name
+func requirements(ctx *gin.Context, name string) ([]requirement, error) {
+	annos := mvc.GetAnnotations(ctx, name)
+	result := make([]requirement, 0, len(annos))
+	for _, anno := range annos {
+		var r requirement
+		if err := anno.Decode(&r); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
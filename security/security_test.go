@@ -0,0 +1,150 @@
+package security_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/gin-plus/v3/security"
+	"github.com/gin-gonic/gin"
+)
+
+// users the principals of the header authenticator, shared between requests
+var users = map[string]*security.Principal{
+	"dev":  {ID: "dev", Roles: []string{"dev"}, Permissions: []string{"order:*"}},
+	"ops":  {ID: "ops", Roles: []string{"ops"}, Permissions: []string{"user:read"}},
+	"root": {ID: "root", Roles: []string{"admin", "ops"}, Permissions: []string{"user:*"}},
+}
+
+// headerAuthenticator authenticate by the X-User header
+type headerAuthenticator struct{}
+
+func (h headerAuthenticator) Authenticate(ctx *gin.Context) (*security.Principal, error) {
+	switch name := ctx.GetHeader("X-User"); name {
+	case "":
+		return nil, nil
+	case "expired":
+		return nil, fmt.Errorf("token of %s, %w", name, security.ErrCredentialsExpired)
+	default:
+		if p, ok := users[name]; ok {
+			return p, nil
+		}
+		return nil, security.ErrUnauthenticated
+	}
+}
+
+// grantProvider grant the roles to every principal
+type grantProvider struct {
+	roles []string
+}
+
+func (g grantProvider) Load(ctx context.Context, principal *security.Principal) ([]string, []string, error) {
+	return g.roles, principal.Permissions, nil
+}
+
+type UserController struct {
+	mvc.Controller
+}
+
+// Whoami responds the id of the principal
+func (u *UserController) Whoami(ctx *gin.Context) {
+	var id string
+	if p, ok := security.Current(ctx); ok {
+		id = p.ID
+	}
+	resp.Json(ctx, id)
+}
+
+func newClient(t *testing.T, provider security.PermissionProvider) *gintest.Client {
+	i := security.New(headerAuthenticator{})
+	if provider != nil {
+		i.PermissionProvider(provider)
+	}
+	return newInterceptorClient(t, i)
+}
+
+func newInterceptorClient(t *testing.T, i *security.Interceptor) *gintest.Client {
+	core.Apis = map[string][]*core.MethodInfo{
+		"UserController": {
+			{Method: http.MethodGet, ApiPath: "/public", Name: "Whoami", Annotations: map[string]string{}},
+			{Method: http.MethodGet, ApiPath: "/profile", Name: "Whoami", Annotations: map[string]string{security.AuthenticatedAnnotation: ""}},
+			{Method: http.MethodGet, ApiPath: "/any", Name: "Whoami", Annotations: map[string]string{security.RequiresRolesAnnotation: `("admin", "ops", logical="any")`}},
+			{Method: http.MethodGet, ApiPath: "/all", Name: "Whoami", Annotations: map[string]string{security.RequiresRolesAnnotation: `("admin", "ops")`}},
+			{Method: http.MethodDelete, ApiPath: "/user", Name: "Whoami", Annotations: map[string]string{security.RequiresPermissionsAnnotation: `("user:delete")`}},
+		},
+	}
+	return gintest.New(t, gintest.WithController(&UserController{}), gintest.WithApp(func(app *application.App) {
+		app.Interceptor(i)
+	})).Client()
+}
+
+func request(c *gintest.Client, method, path, user string) *gintest.Response {
+	req, _ := http.NewRequest(method, path, nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	return c.Do(req)
+}
+
+func TestAuthentication(t *testing.T) {
+	c := newClient(t, nil)
+	for _, tc := range []struct {
+		path, user string
+		status     int
+		code       int
+	}{
+		{"/public", "", http.StatusOK, 0},
+		{"/public", "unknown", http.StatusOK, 0}, // public apis ignore invalid credentials
+		{"/public", "expired", http.StatusOK, 0},
+		{"/public", "dev", http.StatusOK, 0},
+		{"/profile", "", http.StatusUnauthorized, resp.NonLoginCode},
+		{"/profile", "unknown", http.StatusUnauthorized, resp.NonLoginCode},
+		{"/profile", "expired", http.StatusUnauthorized, resp.TokenExpiredCode},
+		{"/profile", "dev", http.StatusOK, 0},
+	} {
+		t.Run(tc.path+" "+tc.user, func(t *testing.T) {
+			request(c, http.MethodGet, tc.path, tc.user).AssertStatus(tc.status).AssertCode(tc.code)
+		})
+	}
+	var id string
+	request(c, http.MethodGet, "/public", "dev").Decode(&id)
+	if id != "dev" {
+		t.Errorf("expected the principal on a public api, got %q", id)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	c := newClient(t, nil)
+	for _, tc := range []struct {
+		method, path, user string
+		code               int
+	}{
+		{http.MethodGet, "/any", "ops", 0},
+		{http.MethodGet, "/any", "dev", resp.ForbiddenCode},
+		{http.MethodGet, "/all", "ops", resp.ForbiddenCode},
+		{http.MethodGet, "/all", "root", 0},
+		{http.MethodDelete, "/user", "root", 0}, // user:* implies user:delete
+		{http.MethodDelete, "/user", "ops", resp.ForbiddenCode},
+		{http.MethodDelete, "/user", "dev", resp.ForbiddenCode},
+	} {
+		t.Run(tc.path+" "+tc.user, func(t *testing.T) {
+			request(c, tc.method, tc.path, tc.user).AssertStatus(http.StatusOK).AssertCode(tc.code)
+		})
+	}
+	request(c, http.MethodGet, "/any", "").AssertStatus(http.StatusUnauthorized).AssertCode(resp.NonLoginCode)
+}
+
+func TestPermissionProvider(t *testing.T) {
+	c := newClient(t, grantProvider{roles: []string{"admin"}})
+	request(c, http.MethodGet, "/any", "dev").AssertCode(0)
+	request(c, http.MethodGet, "/all", "root").AssertCode(resp.ForbiddenCode) // the loaded roles replace the ones of the authenticator
+	if roles := users["dev"].Roles; len(roles) != 1 || roles[0] != "dev" {
+		t.Errorf("expected the shared principal to be copied, got the roles %v", roles)
+	}
+}
@@ -0,0 +1,494 @@
+package security
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token types, stored in the typ claim so that a refresh token cannot be used as an access token
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// JWTConfig the token service configuration, it can be read from app.yml.
+// Example:
+//
+//	security:
+//	  jwt:
+//	    algorithm: RS256
+//	    private_key_file: /etc/app/jwt.pem
+//	    jwks_file: /etc/app/jwks.json
+//	    issuer: my-app
+//	    access_ttl: 15m
+//	    refresh_ttl: 168h
+//	    disable_refresh: false
+//	    claims:
+//	      roles: realm_access.roles
+type JWTConfig struct {
+	Algorithm      string        `mapstructure:"algorithm"`        // Signing algorithm, HS256/384/512, RS256/384/512 or ES256/384/512, default HS256
+	Secret         string        `mapstructure:"secret"`           // Secret of the HS algorithms
+	PrivateKeyFile string        `mapstructure:"private_key_file"` // PEM private key of the RS and ES algorithms
+	KeyID          string        `mapstructure:"key_id"`           // The kid header of the issued tokens
+	JWKSFile       string        `mapstructure:"jwks_file"`        // JWKS file for key rotation, reloaded on change
+	Issuer         string        `mapstructure:"issuer"`           // The iss claim, verified when set
+	Audience       string        `mapstructure:"audience"`         // The aud claim, verified when set
+	AccessTTL      time.Duration `mapstructure:"access_ttl"`       // Access token lifetime, default 15m
+	RefreshTTL     time.Duration `mapstructure:"refresh_ttl"`      // Refresh token lifetime, default 7 days
+	DisableRefresh bool          `mapstructure:"disable_refresh"`  // Issue no refresh token and reject every refresh
+	Leeway         time.Duration `mapstructure:"leeway"`           // Allowed clock skew
+	Claims         ClaimsMapping `mapstructure:"claims"`           // Mapping between the claims and the principal
+}
+
+// ClaimsMapping the claim names of the principal fields, nested claims are separated by dots such as realm_access.roles.
+// Roles and permissions accept an array or a space separated string.
+type ClaimsMapping struct {
+	Subject     string `mapstructure:"subject"`     // default sub
+	Name        string `mapstructure:"name"`        // default name
+	Roles       string `mapstructure:"roles"`       // default roles
+	Permissions string `mapstructure:"permissions"` // default permissions
+}
+
+func (c JWTConfig) withDefaults() JWTConfig {
+	if c.Algorithm == "" {
+		c.Algorithm = "HS256"
+	}
+	if c.AccessTTL <= 0 {
+		c.AccessTTL = 15 * time.Minute
+	}
+	if c.RefreshTTL <= 0 {
+		c.RefreshTTL = 7 * 24 * time.Hour
+	}
+	return c
+}
+
+func (m ClaimsMapping) withDefaults() ClaimsMapping {
+	if m.Subject == "" {
+		m.Subject = "sub"
+	}
+	if m.Name == "" {
+		m.Name = "name"
+	}
+	if m.Roles == "" {
+		m.Roles = "roles"
+	}
+	if m.Permissions == "" {
+		m.Permissions = "permissions"
+	}
+	return m
+}
+
+// Principal map the claims to the principal, all claims are kept in the attributes
+func (m ClaimsMapping) Principal(claims jwt.MapClaims) *Principal {
+	m = m.withDefaults()
+	p := &Principal{Attributes: claims}
+	p.ID, _ = claimValue(claims, m.Subject).(string)
+	p.Name, _ = claimValue(claims, m.Name).(string)
+	p.Roles = stringsClaim(claimValue(claims, m.Roles))
+	p.Permissions = stringsClaim(claimValue(claims, m.Permissions))
+	return p
+}
+
+// Claims map the principal to the claims, the attributes are copied first so that the mapped fields win
+func (m ClaimsMapping) Claims(p *Principal) jwt.MapClaims {
+	m = m.withDefaults()
+	claims := jwt.MapClaims{}
+	for k, v := range p.Attributes {
+		claims[k] = v
+	}
+	setClaim(claims, m.Subject, p.ID)
+	if p.Name != "" {
+		setClaim(claims, m.Name, p.Name)
+	}
+	if len(p.Roles) > 0 {
+		setClaim(claims, m.Roles, p.Roles)
+	}
+	if len(p.Permissions) > 0 {
+		setClaim(claims, m.Permissions, p.Permissions)
+	}
+	return claims
+}
+
+func claimValue(claims map[string]any, path string) any {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := claims[k].(map[string]any)
+		if !ok {
+			return nil
+		}
+		claims = next
+	}
+	return claims[keys[len(keys)-1]]
+}
+
+func setClaim(claims map[string]any, path string, val any) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := claims[k].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			claims[k] = next
+		}
+		claims = next
+	}
+	claims[keys[len(keys)-1]] = val
+}
+
+// RevocationList the revoked tokens by the jti claim, entries can be dropped after the expiration of the token
+type RevocationList interface {
+	Revoke(ctx context.Context, id string, expireAt time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+	// Consume revoke the token unless it is already revoked, atomically, returns false when it was already revoked.
+	// It lets only one of concurrent refreshes redeem a refresh token, such as SET NX with redis
+	Consume(ctx context.Context, id string, expireAt time.Time) (bool, error)
+}
+
+// MemoryRevocationList the in-memory revocation list, the tokens revoked here are still accepted
+// by the other instances until they expire
+type MemoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func (m *MemoryRevocationList) Revoke(ctx context.Context, id string, expireAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoke(id, expireAt)
+	return nil
+}
+
+func (m *MemoryRevocationList) Consume(ctx context.Context, id string, expireAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.revoked[id]; ok {
+		return false, nil
+	}
+	m.revoke(id, expireAt)
+	return true, nil
+}
+
+// revoke drop the expired entries and add the token, the lock must be held
+func (m *MemoryRevocationList) revoke(id string, expireAt time.Time) {
+	if m.revoked == nil {
+		m.revoked = make(map[string]time.Time)
+	}
+	now := time.Now()
+	for k, exp := range m.revoked {
+		if now.After(exp) {
+			delete(m.revoked, k)
+		}
+	}
+	m.revoked[id] = expireAt
+}
+
+func (m *MemoryRevocationList) IsRevoked(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.revoked[id]
+	return ok, nil
+}
+
+// TokenPair the issued tokens
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // Lifetime of the access token in seconds
+}
+
+// TokenService issue, verify, refresh and revoke JWT tokens.
+// It is also an Authenticator, use it with the security interceptor to respond resp.NoLogin or resp.LoginExpired.
+// Example:
+//
+//	var conf security.JWTConfig
+//	_ = application.UnmarshalKey("security.jwt", &conf)
+//	tokens, err := security.NewTokenService(conf)
+//	app.Bean(tokens).Interceptor(security.New(tokens.Revocation(&security.MemoryRevocationList{})))
+type TokenService struct {
+	conf       JWTConfig
+	keys       *KeySet
+	revocation RevocationList
+}
+
+// NewTokenService Create a token service with the configuration, the keys are loaded from the secret,
+// the private key file and the JWKS file
+func NewTokenService(conf JWTConfig) (*TokenService, error) {
+	conf = conf.withDefaults()
+	if jwt.GetSigningMethod(conf.Algorithm) == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm %s", conf.Algorithm)
+	}
+	var keys []Key
+	switch {
+	case conf.Secret != "":
+		keys = append(keys, Key{ID: conf.KeyID, Algorithm: conf.Algorithm, Key: []byte(conf.Secret)})
+	case conf.PrivateKeyFile != "":
+		key, err := loadPrivateKey(conf.PrivateKeyFile, conf.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		key.ID = conf.KeyID
+		keys = append(keys, key)
+	case conf.JWKSFile == "":
+		return nil, errors.New("one of secret, private_key_file and jwks_file is required")
+	}
+	ks := NewKeySet(keys...)
+	if conf.JWKSFile != "" {
+		if err := ks.LoadJWKS(conf.JWKSFile, true); err != nil {
+			return nil, err
+		}
+	}
+	return &TokenService{conf: conf, keys: ks}, nil
+}
+
+// NewTokenServiceWithKeys Create a token service with the key set, the key settings of the configuration are ignored
+func NewTokenServiceWithKeys(conf JWTConfig, keys *KeySet) *TokenService {
+	return &TokenService{conf: conf.withDefaults(), keys: keys}
+}
+
+// Revocation Sets the revocation list, revoked tokens are rejected and used refresh tokens are revoked
+func (s *TokenService) Revocation(list RevocationList) *TokenService {
+	s.revocation = list
+	return s
+}
+
+// Keys the key set of the service
+func (s *TokenService) Keys() *KeySet {
+	return s.keys
+}
+
+// Issue a token pair for the principal
+func (s *TokenService) Issue(ctx context.Context, principal *Principal) (*TokenPair, error) {
+	access, err := s.sign(principal, AccessToken, s.conf.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	pair := &TokenPair{AccessToken: access, TokenType: "Bearer", ExpiresIn: int64(s.conf.AccessTTL.Seconds())}
+	if !s.conf.DisableRefresh {
+		if pair.RefreshToken, err = s.sign(principal, RefreshToken, s.conf.RefreshTTL); err != nil {
+			return nil, err
+		}
+	}
+	return pair, nil
+}
+
+// Verify the access token and returns its principal, an expired token returns an error wrapping ErrCredentialsExpired
+func (s *TokenService) Verify(ctx context.Context, token string) (*Principal, error) {
+	claims, err := s.parse(ctx, token, AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.conf.Claims.Principal(claims), nil
+}
+
+// Refresh issue a new token pair with the refresh token. With a revocation list the refresh token is redeemed once,
+// concurrent refreshes with the same token get ErrUnauthenticated but one. Without it the refresh token can be reused until it expires
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if s.conf.DisableRefresh {
+		return nil, fmt.Errorf("%w, refresh tokens are disabled", ErrUnauthenticated)
+	}
+	claims, err := s.parse(ctx, refreshToken, RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if s.revocation != nil {
+		id, expireAt, err := revocationEntry(claims, s.conf.RefreshTTL)
+		if err != nil {
+			return nil, err
+		}
+		consumed, err := s.revocation.Consume(ctx, id, expireAt)
+		if err != nil {
+			return nil, err
+		}
+		if !consumed {
+			return nil, fmt.Errorf("%w, token revoked", ErrUnauthenticated)
+		}
+	}
+	principal := s.conf.Claims.Principal(claims)
+	principal.Attributes = withoutRegisteredClaims(claims)
+	return s.Issue(ctx, principal)
+}
+
+// Revoke the token until it expires, it requires a revocation list
+func (s *TokenService) Revoke(ctx context.Context, token string) error {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, s.keys.keyFunc, jwt.WithoutClaimsValidation()); err != nil {
+		return fmt.Errorf("%w, %w", ErrUnauthenticated, err)
+	}
+	return s.revoke(ctx, claims)
+}
+
+// Authenticate the bearer token in the Authorization header
+func (s *TokenService) Authenticate(ctx *gin.Context) (*Principal, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return s.Verify(ctx, token)
+}
+
+// Destroy stop watching the JWKS file when the application stops
+func (s *TokenService) Destroy(ctx context.Context) error {
+	return s.keys.Close()
+}
+
+func (s *TokenService) sign(principal *Principal, typ string, ttl time.Duration) (string, error) {
+	key, err := s.keys.signing()
+	if err != nil {
+		return "", err
+	}
+	alg := key.Algorithm
+	if alg == "" {
+		alg = s.conf.Algorithm
+	}
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := s.conf.Claims.Claims(principal)
+	claims["jti"] = hex.EncodeToString(id)
+	claims["typ"] = typ
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	if s.conf.Issuer != "" {
+		claims["iss"] = s.conf.Issuer
+	}
+	if s.conf.Audience != "" {
+		claims["aud"] = s.conf.Audience
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(alg), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.SigningKey)
+}
+
+func (s *TokenService) parse(ctx context.Context, token, typ string) (jwt.MapClaims, error) {
+	options := []jwt.ParserOption{jwt.WithLeeway(s.conf.Leeway), jwt.WithExpirationRequired()}
+	if algorithms := s.keys.algorithms(); len(algorithms) > 0 {
+		options = append(options, jwt.WithValidMethods(algorithms))
+	} else {
+		options = append(options, jwt.WithValidMethods([]string{s.conf.Algorithm}))
+	}
+	if s.conf.Issuer != "" {
+		options = append(options, jwt.WithIssuer(s.conf.Issuer))
+	}
+	if s.conf.Audience != "" {
+		options = append(options, jwt.WithAudience(s.conf.Audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, s.keys.keyFunc, options...)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w, %w", ErrCredentialsExpired, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, %w", ErrUnauthenticated, err)
+	}
+	if actual, _ := claims["typ"].(string); actual != typ {
+		return nil, fmt.Errorf("%w, %s token expected", ErrUnauthenticated, typ)
+	}
+	if err = checkRevoked(ctx, s.revocation, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkRevoked returns an error wrapping ErrUnauthenticated when the token is in the revocation list
+func checkRevoked(ctx context.Context, list RevocationList, claims jwt.MapClaims) error {
+	if list == nil {
+		return nil
+	}
+	id, _ := claims["jti"].(string)
+	revoked, err := list.IsRevoked(ctx, id)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("%w, token revoked", ErrUnauthenticated)
+	}
+	return nil
+}
+
+func (s *TokenService) revoke(ctx context.Context, claims jwt.MapClaims) error {
+	if s.revocation == nil {
+		return nil
+	}
+	id, expireAt, err := revocationEntry(claims, s.conf.RefreshTTL)
+	if err != nil {
+		return err
+	}
+	return s.revocation.Revoke(ctx, id, expireAt)
+}
+
+// revocationEntry the jti claim and how long the token must stay revoked, ttl is used when the token has no exp claim
+func revocationEntry(claims jwt.MapClaims, ttl time.Duration) (string, time.Time, error) {
+	id, _ := claims["jti"].(string)
+	if id == "" {
+		return "", time.Time{}, errors.New("the token has no jti claim")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return id, time.Now().Add(ttl), nil
+	}
+	return id, exp.Time, nil
+}
+
+// withoutRegisteredClaims the custom claims carried over to the refreshed tokens
+func withoutRegisteredClaims(claims jwt.MapClaims) map[string]any {
+	attributes := make(map[string]any, len(claims))
+	for k, v := range claims {
+		switch k {
+		case "iss", "sub", "aud", "exp", "nbf", "iat", "jti", "typ":
+		default:
+			attributes[k] = v
+		}
+	}
+	return attributes
+}
+
+// loadPrivateKey load the PEM private key of the RS or ES algorithm
+func loadPrivateKey(file, algorithm string) (Key, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return Key{}, fmt.Errorf("read jwt private key error, %w", err)
+	}
+	key := Key{Algorithm: algorithm}
+	switch {
+	case strings.HasPrefix(algorithm, "RS") || strings.HasPrefix(algorithm, "PS"):
+		var private *rsa.PrivateKey
+		if private, err = jwt.ParseRSAPrivateKeyFromPEM(content); err == nil {
+			key.Key, key.SigningKey = &private.PublicKey, private
+		}
+	case strings.HasPrefix(algorithm, "ES"):
+		var private *ecdsa.PrivateKey
+		if private, err = jwt.ParseECPrivateKeyFromPEM(content); err == nil {
+			key.Key, key.SigningKey = &private.PublicKey, private
+		}
+	default:
+		return Key{}, fmt.Errorf("algorithm %s does not use a private key file", algorithm)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("parse jwt private key error, %w", err)
+	}
+	return key, nil
+}
+
+// bearerToken the token of the Authorization header
+func bearerToken(ctx *gin.Context) (string, bool) {
+	auth := ctx.GetHeader("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(auth[7:]), true
+}
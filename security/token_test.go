@@ -0,0 +1,157 @@
+package security_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/gin-plus/v3/security"
+)
+
+func newTokens(t *testing.T, conf security.JWTConfig) *security.TokenService {
+	conf.Secret = "secret"
+	tokens, err := security.NewTokenService(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.Revocation(&security.MemoryRevocationList{})
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokens(t, security.JWTConfig{})
+	pair, err := tokens.Issue(ctx, &security.Principal{ID: "1", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := tokens.Verify(ctx, pair.AccessToken)
+	if err != nil || p.ID != "1" || !p.HasRole("admin") {
+		t.Fatalf("expected the principal of the token, got %v, %v", p, err)
+	}
+	if _, err = tokens.Verify(ctx, pair.RefreshToken); !errors.Is(err, security.ErrUnauthenticated) {
+		t.Errorf("expected the refresh token to be rejected as an access token, got %v", err)
+	}
+	if err = tokens.Revoke(ctx, pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tokens.Verify(ctx, pair.AccessToken); !errors.Is(err, security.ErrUnauthenticated) {
+		t.Errorf("expected the revoked token to be rejected, got %v", err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokens(t, security.JWTConfig{AccessTTL: time.Second})
+	pair, err := tokens.Issue(ctx, &security.Principal{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the exp claim has a precision of seconds
+	time.Sleep(2 * time.Second)
+	if _, err = tokens.Verify(ctx, pair.AccessToken); !errors.Is(err, security.ErrCredentialsExpired) {
+		t.Errorf("expected the token to be expired, got %v", err)
+	}
+	if _, err = tokens.Verify(ctx, pair.AccessToken+"x"); errors.Is(err, security.ErrCredentialsExpired) || !errors.Is(err, security.ErrUnauthenticated) {
+		t.Errorf("expected the tampered token to be invalid, got %v", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokens(t, security.JWTConfig{})
+	pair, err := tokens.Issue(ctx, &security.Principal{ID: "1", Attributes: map[string]any{"tenant": "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := tokens.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := tokens.Verify(ctx, refreshed.AccessToken)
+	if err != nil || p.ID != "1" || p.Attributes["tenant"] != "a" {
+		t.Fatalf("expected the principal and the custom claims to be carried over, got %v, %v", p, err)
+	}
+	if _, err = tokens.Refresh(ctx, pair.RefreshToken); !errors.Is(err, security.ErrUnauthenticated) {
+		t.Errorf("expected the used refresh token to be rejected, got %v", err)
+	}
+	if _, err = tokens.Refresh(ctx, refreshed.AccessToken); !errors.Is(err, security.ErrUnauthenticated) {
+		t.Errorf("expected the access token to be rejected as a refresh token, got %v", err)
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokens(t, security.JWTConfig{})
+	pair, err := tokens.Issue(ctx, &security.Principal{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tokens.Refresh(ctx, pair.RefreshToken); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Errorf("expected the refresh token to be redeemed once, got %d", succeeded)
+	}
+}
+
+func TestDisableRefresh(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokens(t, security.JWTConfig{DisableRefresh: true})
+	pair, err := tokens.Issue(ctx, &security.Principal{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.RefreshToken != "" {
+		t.Error("expected no refresh token")
+	}
+	enabled := newTokens(t, security.JWTConfig{})
+	other, err := enabled.Issue(ctx, &security.Principal{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tokens.Refresh(ctx, other.RefreshToken); !errors.Is(err, security.ErrUnauthenticated) {
+		t.Errorf("expected the refresh to be rejected, got %v", err)
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	list := &security.MemoryRevocationList{}
+	tokens := newTokens(t, security.JWTConfig{}).Revocation(list)
+	pair, err := tokens.Issue(ctx, &security.Principal{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := tokens.Issue(ctx, &security.Principal{ID: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tokens.Revoke(ctx, revoked.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	c := newInterceptorClient(t, security.New(&security.JWTAuthenticator{Key: []byte("secret"), Revocation: list}))
+	bearer := func(token string) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, "/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+	c.Do(bearer(pair.AccessToken)).AssertStatus(http.StatusOK).AssertCode(0)
+	c.Do(bearer(pair.RefreshToken)).AssertStatus(http.StatusUnauthorized).AssertCode(resp.NonLoginCode)
+	c.Do(bearer(revoked.AccessToken)).AssertStatus(http.StatusUnauthorized).AssertCode(resp.NonLoginCode)
+}
@@ -0,0 +1,193 @@
+package timeout
+
+import (
+	"context"
+	"github.com/archine/gin-plus/v3/internal/intercept"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/plugin/logger"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// Per-route timeouts, declared by @Timeout or by the timeout block of app.yml.
+// The deadline is set on the context of ctx.Request, handlers pass ctx.Request.Context() to the
+// database, rpc and http calls so that they stop when it is exceeded. Responses written after the
+// deadline, including the error responses of the exception interceptor, are replaced by the timeout result.
+// Example:
+//
+//	var conf timeout.Config
+//	_ = application.UnmarshalKey("timeout", &conf)
+//	app.Interceptor(timeout.New(conf))
+//
+//	// Export
+//	// @GET(path="/export")
+//	// @Timeout("30s")
+//	func (u *UserController) Export(ctx *gin.Context)
+
+// AnnotationName the annotation declaring the timeout of the api
+const AnnotationName = "Timeout"
+
+// writerKey the key of the timeout writer in the gin context
+const writerKey = "gin-plus/timeout/writer"
+
+// RouteTimeout the timeout of the routes matching the method and path
+type RouteTimeout struct {
+	Method  string        `mapstructure:"method"`  // HTTP method, default empty means all methods
+	Path    string        `mapstructure:"path"`    // Route path such as /user/:id, a trailing * matches the prefix
+	Timeout time.Duration `mapstructure:"timeout"` // Zero means no timeout
+}
+
+// Config the timeout configuration.
+// Example:
+//
+//	timeout:
+//	  default: 10s
+//	  routes:
+//	    - path: /export
+//	      method: GET
+//	      timeout: 1m
+type Config struct {
+	Code    int            `mapstructure:"code"`    // Business code of the timed out requests, default resp.TimeoutCode
+	Message string         `mapstructure:"message"` // Message of the timed out requests
+	Default time.Duration  `mapstructure:"default"` // Timeout of the apis without their own timeout, default no timeout
+	Routes  []RouteTimeout `mapstructure:"routes"`  // Timeouts by route, the annotation of the api takes precedence
+}
+
+// annotation the parameters of @Timeout
+type annotation struct {
+	Value time.Duration `anno:"value"`
+}
+
+// Interceptor the timeout interceptor.
+// Keep server.write_timeout longer than the timeouts, otherwise the connection is closed before the result is written.
+type Interceptor struct {
+	conf     Config
+	timeouts intercept.RouteCache[time.Duration] // zero means no timeout
+}
+
+// New Create the timeout interceptor
+func New(conf Config) *Interceptor {
+	if conf.Code == 0 {
+		conf.Code = resp.TimeoutCode
+	}
+	if conf.Message == "" {
+		conf.Message = "请求处理超时，请稍后再试"
+	}
+	return &Interceptor{conf: conf}
+}
+
+func (i *Interceptor) Predicate(ctx *gin.Context) bool {
+	return i.timeout(ctx) > 0
+}
+
+func (i *Interceptor) PreHandle(ctx *gin.Context) {
+	deadlineCtx, cancel := context.WithTimeout(ctx.Request.Context(), i.timeout(ctx))
+	ctx.Request = ctx.Request.WithContext(deadlineCtx)
+	w := &writer{ResponseWriter: ctx.Writer, ctx: ctx, deadline: deadlineCtx, cancel: cancel, conf: &i.conf}
+	ctx.Writer = w
+	ctx.Set(writerKey, w)
+}
+
+func (i *Interceptor) PostHandle(ctx *gin.Context) {}
+
+// AfterCompletion reply the timeout result when the handler gave up without a response, and release the deadline.
+// The writer is kept so that the error response of a panic after the deadline is replaced too
+func (i *Interceptor) AfterCompletion(ctx *gin.Context) {
+	v, ok := ctx.Get(writerKey)
+	if !ok {
+		return
+	}
+	w := v.(*writer)
+	if !w.Written() && w.deadline.Err() == context.DeadlineExceeded {
+		w.timeout()
+	}
+	w.cancel()
+}
+
+// timeout the timeout of the api, resolved on the first request of the route
+func (i *Interceptor) timeout(ctx *gin.Context) time.Duration {
+	return i.timeouts.Load(ctx, func() time.Duration {
+		return i.resolve(ctx)
+	})
+}
+
+// resolve the timeout of the api, priority: annotation > route timeout > default
+func (i *Interceptor) resolve(ctx *gin.Context) time.Duration {
+	d, found := i.conf.Default, false
+	if annos := mvc.GetAnnotations(ctx, AnnotationName); len(annos) > 0 {
+		var anno annotation
+		if err := annos[0].Decode(&anno); err != nil {
+			logger.Log.Errorf("invalid @%s of %s, %s", AnnotationName, ctx.FullPath(), err.Error())
+		} else {
+			d, found = anno.Value, true
+		}
+	}
+	if !found {
+		for _, rt := range i.conf.Routes {
+			if intercept.MatchRoute(rt.Method, rt.Path, ctx.Request.Method, ctx.FullPath()) {
+				d = rt.Timeout
+				break
+			}
+		}
+	}
+	return d
+}
+
+// writer replace the first write after the deadline with the timeout result and discard the rest,
+// responses already started before the deadline are left as they are
+type writer struct {
+	gin.ResponseWriter
+	ctx      *gin.Context
+	deadline context.Context
+	cancel   context.CancelFunc
+	conf     *Config
+	timedOut bool
+	writing  bool // the timeout result is being written
+}
+
+// expired whether the pending write must be discarded
+func (w *writer) expired() bool {
+	if w.writing {
+		return false
+	}
+	if w.timedOut {
+		return true
+	}
+	if w.Written() || w.deadline.Err() != context.DeadlineExceeded {
+		return false
+	}
+	w.timeout()
+	return true
+}
+
+// timeout write the timeout result
+func (w *writer) timeout() {
+	w.timedOut = true
+	w.writing = true
+	defer func() { w.writing = false }()
+	resp.InitResp(w.ctx, http.StatusOK).WithCode(w.conf.Code).WithMessage(w.conf.Message).To()
+	w.ctx.Abort()
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if w.expired() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	if w.expired() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *writer) WriteHeaderNow() {
+	if w.expired() {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
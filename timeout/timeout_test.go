@@ -0,0 +1,80 @@
+package timeout_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/archine/ast-base/core"
+	"github.com/archine/gin-plus/v3/application"
+	"github.com/archine/gin-plus/v3/gintest"
+	"github.com/archine/gin-plus/v3/mvc"
+	"github.com/archine/gin-plus/v3/resp"
+	"github.com/archine/gin-plus/v3/timeout"
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController struct {
+	mvc.Controller
+}
+
+// Late responds after the deadline
+func (r *ReportController) Late(ctx *gin.Context) {
+	<-ctx.Request.Context().Done()
+	resp.Json(ctx, "late")
+}
+
+// Started starts the response before the deadline and finishes it after
+func (r *ReportController) Started(ctx *gin.Context) {
+	ctx.Status(http.StatusOK)
+	_, _ = ctx.Writer.WriteString("start,")
+	<-ctx.Request.Context().Done()
+	_, _ = ctx.Writer.WriteString("end")
+}
+
+// Panic panics after the deadline
+func (r *ReportController) Panic(ctx *gin.Context) {
+	<-ctx.Request.Context().Done()
+	panic(errors.New("report failed"))
+}
+
+func newHarness(t *testing.T, options ...gintest.Option) *gintest.Harness {
+	anno := map[string]string{timeout.AnnotationName: `("50ms")`}
+	core.Apis = map[string][]*core.MethodInfo{
+		"ReportController": {
+			{Method: http.MethodGet, ApiPath: "/late", Name: "Late", Annotations: anno},
+			{Method: http.MethodGet, ApiPath: "/started", Name: "Started", Annotations: anno},
+			{Method: http.MethodGet, ApiPath: "/panic", Name: "Panic", Annotations: anno},
+		},
+	}
+	return gintest.New(t, append(options, gintest.WithController(&ReportController{}), gintest.WithApp(func(app *application.App) {
+		app.Interceptor(timeout.New(timeout.Config{}))
+	}))...)
+}
+
+func TestLateWriteReplaced(t *testing.T) {
+	newHarness(t).Client().Get("/late").AssertStatus(http.StatusOK).AssertCode(resp.TimeoutCode)
+}
+
+func TestStartedWriteKept(t *testing.T) {
+	res := newHarness(t).Client().Get("/started").AssertStatus(http.StatusOK)
+	if body := res.Recorder.Body.String(); body != "start,end" {
+		t.Errorf("expected the response started before the deadline, got %q", body)
+	}
+}
+
+func TestPanicAfterDeadline(t *testing.T) {
+	newHarness(t).Client().Get("/panic").AssertStatus(http.StatusOK).AssertCode(resp.TimeoutCode)
+}
+
+func TestConfigSeconds(t *testing.T) {
+	newHarness(t, gintest.WithConfig("timeout:\n  default: 10\n"))
+	var conf timeout.Config
+	if err := application.UnmarshalKey("timeout", &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Default != 10*time.Second {
+		t.Errorf("expected a bare number to be seconds, got %s", conf.Default)
+	}
+}